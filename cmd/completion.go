@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	ui "github.com/paololazzari/play/src/ui"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate the autocompletion script for the given shell",
+	Long: `Generate the autocompletion script for play for the given shell.
+For instance, to load the completions in the current shell session:
+
+  bash:       source <(play completion bash)
+  zsh:        source <(play completion zsh)
+  fish:       play completion fish | source
+  powershell: play completion powershell | Out-String | Invoke-Expression
+
+To load them in every session, add the same line to the configuration file of the shell.`,
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		default:
+			return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+	},
+}
+
+// Helper function returning a completion function offering the given values
+func completeValues(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// Returns the names of the themes, sorted
+func themeNames() []string {
+	var names []string
+	for name := range ui.Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the programs with a command, followed by the executables found on the PATH, starting with the given prefix
+func completePrograms(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range append(programNames(), executables(toComplete)...) {
+		if strings.HasPrefix(name, toComplete) && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// Returns the executables found on the PATH whose name starts with the given prefix, sorted
+func executables(prefix string) []string {
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+				continue
+			}
+			if _, err := exec.LookPath(filepath.Join(dir, entry.Name())); err == nil {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Register the completions of the positional arguments and flags taking a program or one of a set of values
+func registerCompletions() {
+	runCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// the program is followed by the input files
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return completePrograms(cmd, args, toComplete)
+	}
+	rootCmd.RegisterFlagCompletionFunc("theme", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return themeNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.RegisterFlagCompletionFunc("engine", completeValues("auto", "builtin", "external"))
+	rootCmd.RegisterFlagCompletionFunc("impl", completePrograms)
+	rootCmd.RegisterFlagCompletionFunc("compare", completePrograms)
+	runCmd.RegisterFlagCompletionFunc("files", completeValues(program.FilesAsArguments, program.FilesOnStdin))
+	runCmd.RegisterFlagCompletionFunc("quote", completeValues("single", "double", "none"))
+}
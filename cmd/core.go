@@ -1,11 +1,14 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	ui "github.com/paololazzari/play/src/ui"
 	program "github.com/paololazzari/play/src/util"
@@ -15,10 +18,21 @@ import (
 
 const version = "0.4.0"
 
-func completionCommand() *cobra.Command {
-	return &cobra.Command{
-		Use: "completion",
-	}
+// Commands which never play with a program, for which the PATH is not scanned for plugins
+var pluginlessCommands = map[string]bool{
+	"version":    true,
+	"completion": true,
+}
+
+// Commands which do not evaluate a program, whose flags are neither validated nor shown
+var utilityCommands = map[string]bool{
+	"version":                       true,
+	"completion":                    true,
+	"list":                          true,
+	"doctor":                        true,
+	"help":                          true,
+	cobra.ShellCompRequestCmd:       true,
+	cobra.ShellCompNoDescRequestCmd: true,
 }
 
 var (
@@ -27,18 +41,31 @@ var (
 		Short: "play",
 		Long:  `play`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			if cmd.Use != "version" {
-				validateProgramExists(cmd.Use)
-
-				theme, _ := cmd.Flags().GetString("theme")
-				validateThemeSupport(theme)
+			if !utilityCommands[cmd.Name()] {
+				validateArgs(cmd, args)
 				if cmd.Annotations == nil {
 					cmd.Annotations = make(map[string]string)
 				}
+				if program.IsPlugin(cmd.Use) && cmd != runCmd {
+					validatePlugin(cmd)
+				} else if useBuiltinEngine(cmd, args) {
+					cmd.Annotations["engine"] = "builtin"
+				} else if implementation, _ := cmd.Flags().GetString("impl"); len(implementation) == 0 {
+					validateProgramExists(executable(cmd, args))
+				}
+
+				theme, _ := cmd.Flags().GetString("theme")
+				validateThemeSupport(theme)
 				cmd.Annotations["theme"] = theme
-			}
 
-			validateArgs(args)
+				validateSettings(getSettings(cmd))
+				if maxStdin, _ := cmd.Flags().GetInt64("max-stdin"); maxStdin < 0 {
+					exitWithError(fmt.Sprintf("Error: Invalid max stdin '%d'", maxStdin))
+				}
+				if interval, _ := cmd.Flags().GetDuration("follow-interval"); interval <= 0 {
+					exitWithError("Error: Invalid follow interval '" + interval.String() + "'")
+				}
+			}
 			return nil
 		},
 	}
@@ -47,6 +74,7 @@ var (
 		Use:   "version",
 		Short: "Print version number",
 		Long:  `Print version number`,
+		Args:  cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Println("play", version)
 		},
@@ -55,40 +83,65 @@ var (
 	grepCmd = &cobra.Command{
 		Use:   "grep",
 		Short: `Play with grep`,
+		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			run(program.NewProgram("grep", true), cmd.Annotations["theme"])
+			run(program.Builtins["grep"], cmd, args)
 		},
 	}
 
 	sedCmd = &cobra.Command{
 		Use:   "sed",
 		Short: `Play with sed`,
+		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			run(program.NewProgram("sed", true), cmd.Annotations["theme"])
+			run(program.Builtins["sed"], cmd, args)
 		},
 	}
 
 	awkCmd = &cobra.Command{
 		Use:   "awk",
 		Short: `Play with awk`,
+		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			run(program.NewProgram("awk", true), cmd.Annotations["theme"])
+			run(program.Builtins["awk"], cmd, args)
 		},
 	}
 
 	jqCmd = &cobra.Command{
 		Use:   "jq",
 		Short: `Play with jq`,
+		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			run(program.NewProgram("jq", false), cmd.Annotations["theme"])
+			run(program.Builtins["jq"], cmd, args)
 		},
 	}
 
 	yqCmd = &cobra.Command{
 		Use:   "yq",
 		Short: `Play with yq`,
+		Args:  cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			run(program.Builtins["yq"], cmd, args)
+		},
+	}
+
+	regexCmd = &cobra.Command{
+		Use:   "regex",
+		Short: `Play with Go regular expressions`,
+		Args:  cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			run(program.Builtins["regex"], cmd, args)
+		},
+	}
+
+	runCmd = &cobra.Command{
+		Use:   "run <program>",
+		Short: `Play with any program`,
+		Long: `Play with any program, describing the shape of its arguments with flags.
+For instance: play run tr --files stdin --template "{options} {expr1} {expr2}"`,
+		Args: cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			run(program.NewProgram("yq", false), cmd.Annotations["theme"])
+			run(getProgram(cmd, args[0]), cmd, args[1:])
 		},
 	}
 )
@@ -98,12 +151,166 @@ func exitWithError(e interface{}) {
 	os.Exit(1)
 }
 
-func validateArgs(args []string) {
-	if len(args) > 0 {
+// Commands which do not validate their positional arguments accept none.
+// The positional arguments of the commands playing with programs are their input files.
+func validateArgs(cmd *cobra.Command, args []string) {
+	if cmd.Args == nil && len(args) > 0 {
 		exitWithError("Invalid number of arguments")
 	}
 }
 
+// Returns the executable of the program played with
+func executable(cmd *cobra.Command, args []string) string {
+	if cmd == runCmd {
+		return args[0]
+	}
+	return program.Lookup(cmd.Use).Executable()
+}
+
+// Returns whether the program played with is evaluated by its builtin engine.
+// With the auto engine, the builtin engine is used when the executable of the program is not found.
+func useBuiltinEngine(cmd *cobra.Command, args []string) bool {
+	engine, _ := cmd.Flags().GetString("engine")
+	implementation, _ := cmd.Flags().GetString("impl")
+	name := cmd.Use
+	if cmd == runCmd {
+		name = args[0]
+	}
+	_, available := program.BuiltinEngine(name)
+
+	if program.BuiltinOnly(name) {
+		switch engine {
+		case "auto":
+			engine = "builtin"
+		case "external":
+			exitWithError(fmt.Sprintf("Error: %s is only available as a builtin engine", name))
+		}
+	}
+	switch engine {
+	case "auto":
+		if !available || len(implementation) > 0 {
+			return false
+		}
+		_, err := exec.LookPath(executable(cmd, args))
+		return err != nil
+	case "builtin":
+		if !available {
+			exitWithError(fmt.Sprintf("Error: %s has no builtin engine", name))
+		}
+		if len(implementation) > 0 {
+			exitWithError("Error: --impl cannot be used with the builtin engine")
+		}
+		return true
+	case "external":
+		return false
+	default:
+		exitWithError(fmt.Sprintf("Error: Invalid engine '%s'. Valid engines are: [auto builtin external]", engine))
+	}
+	return false
+}
+
+// Returns the program given to the run command, with the shape of its arguments given by the flags
+func getProgram(cmd *cobra.Command, name string) program.Program {
+	endOfOptions, _ := cmd.Flags().GetBool("end-of-options")
+	files, _ := cmd.Flags().GetString("files")
+	quote, _ := cmd.Flags().GetString("quote")
+	template, _ := cmd.Flags().GetString("template")
+
+	p := program.NewProgram(name, endOfOptions)
+	switch files {
+	case program.FilesAsArguments, program.FilesOnStdin:
+		p.FilePlacement = files
+	default:
+		exitWithError(fmt.Sprintf("Error: Invalid file placement '%s'. Valid placements are: [%s %s]", files, program.FilesAsArguments, program.FilesOnStdin))
+	}
+	if _, ok := program.QuoteStyles[quote]; !ok {
+		exitWithError(fmt.Sprintf("Error: Invalid quote '%s'. Valid quotes are: [single double none]", quote))
+	}
+	p.Quote = program.QuoteStyles[quote]
+	if len(template) > 0 {
+		if _, err := program.ParseTemplate(template); err != nil {
+			exitWithError(fmt.Sprintf("Error: Invalid template '%s': %s", template, err))
+		}
+		p.Template = template
+	}
+	return p
+}
+
+// Returns the command playing with the program of the given profile
+func profileCommand(p program.Program) *cobra.Command {
+	short := p.Description
+	if len(short) == 0 {
+		short = "Play with " + p.Name
+	}
+	return &cobra.Command{
+		Use:   p.Name,
+		Short: short,
+		Args:  cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			run(p, cmd, args)
+		},
+	}
+}
+
+// Register a command for each profile of the profiles directory.
+// Invalid profiles and profiles named after an existing command are skipped with a warning.
+func registerProfiles() {
+	dir, err := program.ProfilesDir()
+	if err != nil {
+		return
+	}
+	programs, errs := program.LoadProfiles(dir)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+	}
+	for _, p := range programs {
+		if existing, _, err := rootCmd.Find([]string{p.Name}); err == nil && existing != rootCmd {
+			fmt.Fprintf(os.Stderr, "Warning: profile %s is ignored, as it is named after an existing command\n", p.Name)
+			continue
+		}
+		program.RegisterProfile(p)
+		rootCmd.AddCommand(profileCommand(p))
+	}
+}
+
+// Returns whether the plugins are needed to execute the given arguments,
+// which is the case unless they name a command which never plays with a program
+func needsPlugins(args []string) bool {
+	cmd, _, err := rootCmd.Find(args)
+	return err != nil || cmd == rootCmd || !pluginlessCommands[cmd.Name()]
+}
+
+// Register a command for each plugin found on the PATH.
+// Plugins named after an existing command are skipped with a warning.
+func registerPlugins() {
+	found := program.FindPlugins()
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if existing, _, err := rootCmd.Find([]string{name}); err == nil && existing != rootCmd {
+			fmt.Fprintf(os.Stderr, "Warning: plugin %s is ignored, as it is named after an existing command\n", found[name])
+			continue
+		}
+		rootCmd.AddCommand(profileCommand(program.RegisterPlugin(name, found[name])))
+	}
+}
+
+// Plugins are only evaluated by themselves
+func validatePlugin(cmd *cobra.Command) {
+	engine, _ := cmd.Flags().GetString("engine")
+	if engine != "auto" {
+		exitWithError("Error: --engine cannot be used with plugins")
+	}
+	for _, flag := range []string{"impl", "compare"} {
+		if implementation, _ := cmd.Flags().GetString(flag); len(implementation) > 0 {
+			exitWithError(fmt.Sprintf("Error: --%s cannot be used with plugins", flag))
+		}
+	}
+}
+
 func validateThemeSupport(theme string) {
 	var validThemes []string
 
@@ -117,6 +324,49 @@ func validateThemeSupport(theme string) {
 	}
 }
 
+func getSettings(cmd *cobra.Command) program.Settings {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	maxOutput, _ := cmd.Flags().GetInt("max-output")
+	shell, _ := cmd.Flags().GetBool("shell")
+	sandbox, _ := cmd.Flags().GetBool("sandbox")
+	scratch, _ := cmd.Flags().GetBool("scratch")
+	cacheSize, _ := cmd.Flags().GetInt("cache-size")
+	benchmarkRuns, _ := cmd.Flags().GetInt("benchmark-runs")
+	benchmarkWarmup, _ := cmd.Flags().GetInt("benchmark-warmup")
+	var cache *program.Cache
+	if cacheSize > 0 {
+		cache = program.NewCache(cacheSize)
+	}
+	return program.Settings{
+		Timeout:         timeout,
+		MaxOutput:       maxOutput,
+		Shell:           shell,
+		Sandbox:         sandbox,
+		Scratch:         scratch,
+		Cache:           cache,
+		BenchmarkRuns:   benchmarkRuns,
+		BenchmarkWarmup: benchmarkWarmup,
+	}
+}
+
+func validateSettings(settings program.Settings) {
+	if settings.Timeout < 0 {
+		exitWithError("Error: Invalid timeout '" + settings.Timeout.String() + "'")
+	}
+	if settings.MaxOutput < 0 {
+		exitWithError(fmt.Sprintf("Error: Invalid max output '%d'", settings.MaxOutput))
+	}
+	if settings.BenchmarkRuns <= 0 {
+		exitWithError(fmt.Sprintf("Error: Invalid number of benchmark runs '%d'", settings.BenchmarkRuns))
+	}
+	if settings.BenchmarkWarmup < 0 {
+		exitWithError(fmt.Sprintf("Error: Invalid number of benchmark warmup runs '%d'", settings.BenchmarkWarmup))
+	}
+	if settings.Sandbox && !program.SandboxSupported() {
+		exitWithError("Error: Sandbox mode is only supported on Linux")
+	}
+}
+
 func validateProgramExists(program string) {
 	_, err := exec.LookPath(program)
 	if err != nil {
@@ -124,65 +374,295 @@ func validateProgramExists(program string) {
 	}
 }
 
-func run(program program.Program, theme string) error {
+// Returns the program using the implementation given by the flag, if any
+func getImplementation(p program.Program, cmd *cobra.Command, flag string) (program.Program, bool) {
+	implementation, _ := cmd.Flags().GetString(flag)
+	if len(implementation) == 0 {
+		return p, false
+	}
+	p, err := p.WithImplementation(implementation)
+	if err != nil {
+		exitWithError(fmt.Sprintf("Error: Invalid implementation '%s': %s", implementation, err))
+	}
+	return p, true
+}
 
-	var userInterface *ui.UI
-	var stdinTmpFile string
-	input := ""
-
-	// check whether file descriptor is terminal
-	if !term.IsTerminal(int(os.Stdin.Fd())) {
-		if runtime.GOOS != "windows" {
-			_, err := os.OpenFile("/dev/tty", os.O_RDONLY, 0)
-			if err != nil {
-				exitWithError(err)
+// Returns the expressions given by the flags, either directly or in files
+func getExpressions(cmd *cobra.Command, p program.Program) []string {
+	expressions, _ := cmd.Flags().GetStringArray("expr")
+	expressionFiles, _ := cmd.Flags().GetStringArray("expr-file")
+	if len(expressions) > 0 && len(expressionFiles) > 0 {
+		exitWithError("Error: --expr and --expr-file cannot be used together")
+	}
+	for _, path := range expressionFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			exitWithError(fmt.Sprintf("Error: Invalid expression file: %s", err))
+		}
+		expressions = append(expressions, strings.TrimSuffix(string(data), "\n"))
+	}
+
+	if n := expressionCount(p); len(expressions) > n {
+		exitWithError(fmt.Sprintf("Error: Too many expressions for %s: %d given, at most %d accepted", p.Name, len(expressions), n))
+	}
+	return expressions
+}
+
+// Returns the number of expressions taken by the given program.
+// Without a template, programs take a single expression.
+func expressionCount(p program.Program) int {
+	if len(p.Template) == 0 {
+		return 1
+	}
+	parts, _ := program.ParseTemplate(p.Template)
+	return program.TemplateExpressions(parts)
+}
+
+// Returns the given arguments with -- inserted before the first flag unknown to the command playing with a program,
+// so that this flag and the following arguments are taken as the words of the command instead of being parsed
+func stopAtUnknownFlag(args []string) []string {
+	cmd, rest, err := rootCmd.Find(args)
+	if err != nil || cmd == rootCmd || utilityCommands[cmd.Name()] {
+		return args
+	}
+	// -h and --help are play's only when given alone, as in "play grep -h"
+	if len(rest) == 1 && (rest[0] == "-h" || rest[0] == "--help") {
+		return args
+	}
+	stop := func(i int) []string {
+		return append(append(append([]string{}, args[:i]...), "--"), args[i:]...)
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return args
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			continue
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			flag := cmd.Flags().Lookup(name)
+			if flag == nil {
+				flag = cmd.InheritedFlags().Lookup(name)
 			}
-			var stdin []byte
-			scanner := bufio.NewScanner(os.Stdin)
-			for scanner.Scan() {
-				stdin = append(stdin, scanner.Bytes()...)
-				stdin = append(stdin, '\n')
+			if flag == nil || flag.Name == "help" {
+				return stop(i)
 			}
-			input = string(stdin)
-
-			tmpFile, err := os.CreateTemp("", "play")
-			if err != nil {
-				exitWithError(err)
+			if !hasValue && len(flag.NoOptDefVal) == 0 {
+				i++
+			}
+		default:
+			// shorthands can be combined, as in -ab, the last one taking a value if needed
+			for j := 1; j < len(arg); j++ {
+				flag := cmd.Flags().ShorthandLookup(arg[j : j+1])
+				if flag == nil {
+					flag = cmd.InheritedFlags().ShorthandLookup(arg[j : j+1])
+				}
+				if flag == nil || flag.Name == "help" {
+					return stop(i)
+				}
+				if len(flag.NoOptDefVal) == 0 {
+					if j == len(arg)-1 {
+						i++
+					}
+					break
+				}
 			}
-			defer tmpFile.Close()
+		}
+	}
+	return args
+}
 
-			_, err = tmpFile.Write([]byte(input))
-			if err != nil {
-				exitWithError(err)
+// Helper function to split the arguments into the input files and the words of the command, which follow --
+func splitArgs(cmd *cobra.Command, args []string) ([]string, []string) {
+	// the arguments may leave out the first ones, such as the program given to play run
+	dash := cmd.ArgsLenAtDash() - (len(cmd.Flags().Args()) - len(args))
+	if dash < 0 {
+		return args, nil
+	}
+	// the input files are appended to, which must not overwrite the words
+	return args[:dash:dash], args[dash:]
+}
+
+// Helper function to split the words of a command into its options, its expressions and its input files.
+// The options are the first words starting with a dash, followed by as many expressions as the program takes.
+func splitWords(p program.Program, words []string) ([]string, []string, []string) {
+	i := 0
+	for i < len(words) && strings.HasPrefix(words[i], "-") && words[i] != "-" {
+		i++
+	}
+	j := i + expressionCount(p)
+	if j > len(words) {
+		j = len(words)
+	}
+	return words[:i], words[i:j], words[j:]
+}
+
+// Returns the given input files, relative to the working directory when they are inside it
+func getInputFiles(paths []string) []string {
+	wd, _ := os.Getwd()
+	var files []string
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			exitWithError(path + " not found")
+		}
+		if stat.IsDir() {
+			exitWithError(path + " is a directory")
+		}
+		if filepath.IsAbs(path) {
+			if rel, err := filepath.Rel(wd, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				path = rel
 			}
-			stdinTmpFile = tmpFile.Name()
 		}
+		files = append(files, filepath.Clean(path))
 	}
+	return files
+}
 
-	userInterface = ui.NewUI(program.Name, program.RespectsEndOfOptions, stdinTmpFile, theme)
+// Returns the capture of the standard input if it is not a terminal, or nil otherwise.
+// The standard input is read in the background, so that the user interface starts right away.
+func captureStdin(cmd *cobra.Command, files []string) *program.Capture {
+	if term.IsTerminal(int(os.Stdin.Fd())) || runtime.GOOS == "windows" {
+		return nil
+	}
+	if len(files) > 0 {
+		exitWithError("Error: input files cannot be given along with standard input")
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDONLY, 0)
+	if err != nil {
+		exitWithError(err)
+	}
+	tty.Close()
+	maxStdin, _ := cmd.Flags().GetInt64("max-stdin")
+	follow, _ := cmd.Flags().GetBool("follow")
+	stdin, err := program.CaptureInput(os.Stdin, maxStdin, follow)
+	if err != nil {
+		exitWithError(err)
+	}
+	return stdin
+}
+
+func run(p program.Program, cmd *cobra.Command, args []string) error {
+	theme := cmd.Annotations["theme"]
+	settings := getSettings(cmd)
+	args, words := splitArgs(cmd, args)
+	options, wordExpressions, wordFiles := splitWords(p, words)
+	if cmd.Flags().Changed("options") {
+		if len(options) > 0 {
+			exitWithError("Error: --options cannot be used along with options given as arguments")
+		}
+		p.Options, _ = cmd.Flags().GetString("options")
+	} else if len(options) > 0 {
+		p.Options = program.JoinOptions(options)
+	}
+	expressions := getExpressions(cmd, p)
+	if len(wordExpressions) > 0 {
+		if len(expressions) > 0 {
+			exitWithError("Error: --expr and --expr-file cannot be used along with expressions given as arguments")
+		}
+		expressions = wordExpressions
+	}
+	files := getInputFiles(append(args, wordFiles...))
+	var compare *program.Program
+	if alternative, ok := getImplementation(p, cmd, "compare"); ok {
+		compare = &alternative
+	}
+	program, _ := getImplementation(p, cmd, "impl")
+	if cmd.Annotations["engine"] == "builtin" {
+		program, _ = program.WithBuiltinEngine()
+	}
+
+	var userInterface *ui.UI
+	stdin := captureStdin(cmd, files)
+
+	userInterface = ui.NewUI(program, compare, stdin, theme, settings)
+	userInterface.Preload(expressions, files)
+	if follow, _ := cmd.Flags().GetBool("follow"); follow {
+		if stdin == nil {
+			exitWithError("Error: --follow requires the standard input to be piped")
+		}
+		interval, _ := cmd.Flags().GetDuration("follow-interval")
+		userInterface.Follow(interval)
+	}
 	userInterface.InitUI()
 	userInterface.Run()
 	return nil
 }
 
 func init() {
-	completion := completionCommand()
-	completion.Hidden = true
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(grepCmd)
 	rootCmd.AddCommand(sedCmd)
 	rootCmd.AddCommand(awkCmd)
 	rootCmd.AddCommand(jqCmd)
 	rootCmd.AddCommand(yqCmd)
-	rootCmd.AddCommand(completion) // https://github.com/spf13/cobra/issues/1507
+	rootCmd.AddCommand(regexCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().Bool("json", false, "print the checks as JSON")
+	runCmd.Flags().Bool("end-of-options", true, "whether the program accepts -- to mark the end of its options")
+	runCmd.Flags().String("files", program.FilesAsArguments, "how the input files are passed to the program: "+program.FilesAsArguments+" or "+program.FilesOnStdin)
+	runCmd.Flags().String("quote", "single", "quote initially used around the positional arguments: single, double or none")
+	runCmd.Flags().String("template", "", "arguments of the program, e.g. \"{options} {expr1} {expr2} {files}\"")
 	rootCmd.PersistentFlags().String("theme", "monokai", "theme")
-	versionCmd.SetHelpFunc(func(command *cobra.Command, strings []string) {
+	rootCmd.PersistentFlags().Duration("timeout", 5*time.Second, "maximum duration of each evaluation (0 for no limit)")
+	rootCmd.PersistentFlags().Int("max-output", 1<<20, "maximum number of bytes of output captured per evaluation (0 for no limit)")
+	rootCmd.PersistentFlags().Int("cache-size", 64, "number of evaluation results kept in memory (0 to disable caching)")
+	rootCmd.PersistentFlags().Int("benchmark-runs", 10, "number of measured runs of a benchmark")
+	rootCmd.PersistentFlags().Int("benchmark-warmup", 2, "number of unmeasured runs preceding a benchmark")
+	rootCmd.PersistentFlags().String("impl", "", "implementation of the program to use, e.g. \"mawk\" or \"busybox awk\"")
+	rootCmd.PersistentFlags().String("engine", "auto", "how the program is evaluated: external (its executable), builtin (within play, for jq, awk and regex) or auto (builtin if the executable is not found)")
+	rootCmd.PersistentFlags().String("compare", "", "implementation of the program to compare against, e.g. \"busybox awk\"")
+	rootCmd.PersistentFlags().String("options", "", "command options initially used")
+	rootCmd.PersistentFlags().StringArray("expr", nil, "expression initially used, repeated for programs taking several expressions")
+	rootCmd.PersistentFlags().StringArray("expr-file", nil, "file holding the expression initially used, repeated for programs taking several expressions")
+	rootCmd.PersistentFlags().Int64("max-stdin", 100<<20, "maximum number of bytes of standard input read, the rest being discarded (0 for no limit); in follow mode, the oldest input is discarded instead")
+	rootCmd.PersistentFlags().Bool("follow", false, "follow the standard input as it grows, like tail -f, evaluating the expressions again on new input")
+	rootCmd.PersistentFlags().Duration("follow-interval", time.Second, "minimum interval between the evaluations on new input in follow mode")
+	rootCmd.PersistentFlags().Bool("shell", false, "evaluate the command through the shell, allowing pipes and globbing in the command options")
+	rootCmd.PersistentFlags().Bool("scratch", false, "evaluate the command on scratch copies of the input files and preview the changes made to them")
+	rootCmd.PersistentFlags().Bool("sandbox", false, "evaluate the command with a read-only working directory, a private /tmp and no network (Linux only)")
+	registerCompletions()
+	utilityHelp := func(command *cobra.Command, strings []string) {
 		command.Flags().MarkHidden("theme")
+		command.Flags().MarkHidden("timeout")
+		command.Flags().MarkHidden("max-output")
+		command.Flags().MarkHidden("cache-size")
+		command.Flags().MarkHidden("benchmark-runs")
+		command.Flags().MarkHidden("benchmark-warmup")
+		command.Flags().MarkHidden("impl")
+		command.Flags().MarkHidden("engine")
+		command.Flags().MarkHidden("compare")
+		command.Flags().MarkHidden("options")
+		command.Flags().MarkHidden("expr")
+		command.Flags().MarkHidden("expr-file")
+		command.Flags().MarkHidden("max-stdin")
+		command.Flags().MarkHidden("follow")
+		command.Flags().MarkHidden("follow-interval")
+		command.Flags().MarkHidden("shell")
+		command.Flags().MarkHidden("sandbox")
+		command.Flags().MarkHidden("scratch")
 		command.Parent().HelpFunc()(command, strings)
-	})
+	}
+	versionCmd.SetHelpFunc(utilityHelp)
+	completionCmd.SetHelpFunc(utilityHelp)
+	listCmd.SetHelpFunc(utilityHelp)
+	doctorCmd.SetHelpFunc(utilityHelp)
 }
 
 func Execute() {
+	if len(os.Args) > 1 && os.Args[1] == program.SandboxCommand {
+		program.SandboxMain(os.Args[2:])
+	}
+	registerProfiles()
+	if needsPlugins(os.Args[1:]) {
+		registerPlugins()
+	}
+	rootCmd.SetArgs(stopAtUnknownFlag(os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
 		exitWithError(err)
 	}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gdamore/tcell/v2"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/spf13/cobra"
+)
+
+// Statuses of the checks of the environment
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// Result of a check of the environment
+type check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// Programs played with by the dedicated commands, whose executables are checked
+var doctorPrograms = []string{"grep", "sed", "awk", "jq", "yq"}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment play runs in",
+	Long: `Check the environment play runs in: the color capability of the terminal, the availability of /dev/tty
+for reading the standard input, the shell used with --shell, the programs played with and their flavor
+(GNU, BSD or busybox), the temporary directory and the location of the configuration.
+The command fails if any of the checks fails. A missing program only warns, as the other commands still work.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		checks := []check{checkTerminal(), checkTty(), checkShell()}
+		for _, name := range doctorPrograms {
+			checks = append(checks, checkProgram(ctx, name))
+		}
+		checks = append(checks, checkTempDir(), checkProfilesDir())
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(checks)
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "CHECK\tSTATUS\tDETAIL")
+			for _, c := range checks {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Status, c.Detail)
+			}
+			w.Flush()
+		}
+		for _, c := range checks {
+			if c.Status == checkFail {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// Check the colors supported by the terminal, as told by its terminfo description
+func checkTerminal() check {
+	c := check{Name: "terminal"}
+	if runtime.GOOS == "windows" {
+		c.Status, c.Detail = checkPass, "Windows console"
+		return c
+	}
+	term := os.Getenv("TERM")
+	if len(term) == 0 {
+		c.Status, c.Detail = checkFail, "TERM is not set"
+		return c
+	}
+	ti, err := tcell.LookupTerminfo(term)
+	if err != nil {
+		c.Status, c.Detail = checkFail, fmt.Sprintf("TERM=%s: %s", term, err)
+		return c
+	}
+	truecolor := (len(ti.SetFgBgRGB) > 0 || len(ti.SetFgRGB) > 0 || len(ti.SetBgRGB) > 0) && os.Getenv("TCELL_TRUECOLOR") != "disable"
+	switch {
+	case truecolor:
+		c.Status, c.Detail = checkPass, fmt.Sprintf("TERM=%s, true color", term)
+	case ti.Colors >= 256:
+		c.Status, c.Detail = checkPass, fmt.Sprintf("TERM=%s, %d colors", term, ti.Colors)
+	default:
+		c.Status, c.Detail = checkWarn, fmt.Sprintf("TERM=%s, %d colors: the colors of the themes are approximated", term, ti.Colors)
+	}
+	return c
+}
+
+// Check that /dev/tty can be opened, as the user interface reads from it when the standard input is piped
+func checkTty() check {
+	c := check{Name: "/dev/tty"}
+	if runtime.GOOS == "windows" {
+		c.Status, c.Detail = checkWarn, "the standard input is not read on Windows"
+		return c
+	}
+	f, err := os.OpenFile("/dev/tty", os.O_RDONLY, 0)
+	if err != nil {
+		c.Status, c.Detail = checkFail, fmt.Sprintf("%s: the standard input cannot be piped to play", err)
+		return c
+	}
+	f.Close()
+	c.Status, c.Detail = checkPass, "available"
+	return c
+}
+
+// Check that the shell used in shell mode is found
+func checkShell() check {
+	shell := program.Shell()[0]
+	c := check{Name: "shell"}
+	path, err := exec.LookPath(shell)
+	if err != nil {
+		c.Status, c.Detail = checkWarn, fmt.Sprintf("%s not found: --shell cannot be used", shell)
+		return c
+	}
+	c.Status, c.Detail = checkPass, path
+	return c
+}
+
+// Check that the executable of the given program is found, and tell its version and flavor.
+// Missing programs are only a warning if they can be evaluated by a builtin engine.
+func checkProgram(ctx context.Context, name string) check {
+	c := check{Name: name}
+	p := program.Builtins[name]
+	path, err := p.ResolvedPath()
+	if err != nil {
+		if engine, ok := program.BuiltinEngine(name); ok {
+			c.Status, c.Detail = checkWarn, fmt.Sprintf("not found: evaluated by the builtin engine (%s)", program.EngineVersion(engine))
+		} else {
+			c.Status, c.Detail = checkWarn, fmt.Sprintf("not found: play %s cannot be used", name)
+		}
+		return c
+	}
+	version := p.Version(ctx)
+	details := []string{path}
+	if flavor := program.Flavor(path, version); len(flavor) > 0 {
+		details = append(details, flavor)
+	}
+	if len(version) > 0 {
+		details = append(details, version)
+	} else {
+		details = append(details, "unknown version")
+	}
+	c.Status, c.Detail = checkPass, strings.Join(details, ", ")
+	return c
+}
+
+// Check that the temporary directory, holding the standard input and scratch copies, is writable
+func checkTempDir() check {
+	c := check{Name: "temp dir"}
+	f, err := os.CreateTemp("", "play-doctor")
+	if err == nil {
+		_, err = f.WriteString("play")
+		f.Close()
+		os.Remove(f.Name())
+	}
+	if err != nil {
+		c.Status, c.Detail = checkFail, err.Error()
+		return c
+	}
+	c.Status, c.Detail = checkPass, os.TempDir()
+	return c
+}
+
+// Check that the profiles of the configuration directory are valid, if any
+func checkProfilesDir() check {
+	c := check{Name: "profiles"}
+	dir, err := program.ProfilesDir()
+	if err != nil {
+		c.Status, c.Detail = checkWarn, err.Error()
+		return c
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		c.Status, c.Detail = checkPass, dir+" (not present)"
+		return c
+	}
+	programs, errs := program.LoadProfiles(dir)
+	if len(errs) > 0 {
+		c.Status, c.Detail = checkWarn, fmt.Sprintf("%s: %s", dir, errs[0])
+		return c
+	}
+	c.Status, c.Detail = checkPass, fmt.Sprintf("%s (%d profiles)", dir, len(programs))
+	return c
+}
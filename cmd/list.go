@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	program "github.com/paololazzari/play/src/util"
+	"github.com/spf13/cobra"
+)
+
+// Programs with a dedicated command, in the order they are listed
+var builtinCommands = []string{"grep", "sed", "awk", "jq", "yq", "regex"}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the supported programs",
+	Long: `List the programs with a dedicated command, including those defined by profiles and plugins,
+along with whether their executable is found, its path and its version.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROGRAM\tFOUND\tPATH\tVERSION")
+		pluginPaths := program.FindPlugins()
+		for _, name := range programNames() {
+			found, path, version := "plugin", pluginPaths[name], "-"
+			if !program.IsPlugin(name) {
+				found, path, version = describeProgram(cmd.Context(), name)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, found, path, version)
+		}
+		w.Flush()
+	},
+}
+
+// Returns the names of the programs with a dedicated command: the builtin ones, then those of the profiles and plugins, sorted
+func programNames() []string {
+	others := append(program.ProfileNames(), program.PluginNames()...)
+	sort.Strings(others)
+	return append(append([]string{}, builtinCommands...), others...)
+}
+
+// Helper function to tell whether the executable of the program of the given name is found, its path and its version.
+// Programs which are not found are described by their builtin engine, if any.
+func describeProgram(ctx context.Context, name string) (string, string, string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	engine, hasEngine := program.BuiltinEngine(name)
+	if program.BuiltinOnly(name) {
+		return "builtin", "-", program.EngineVersion(engine)
+	}
+	p := program.Lookup(name)
+	path, err := p.ResolvedPath()
+	if err != nil {
+		if hasEngine {
+			return "no", "-", program.EngineVersion(engine) + " (builtin)"
+		}
+		return "no", "-", "-"
+	}
+	version := p.Version(ctx)
+	if len(version) == 0 {
+		version = "unknown"
+	}
+	return "yes", path, version
+}
@@ -0,0 +1,231 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Number of unchanged lines shown around each change
+const contextLines = 3
+
+// Kind of edit
+type operation int
+
+const (
+	equal operation = iota
+	insert
+	remove
+)
+
+// Single line edit, along with the position of the line in both texts
+type edit struct {
+	op    operation
+	line  string
+	left  int
+	right int
+}
+
+// Helper function to split a text in lines, keeping line terminators
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines of two texts being compared, identified by numbers so that they are compared cheaply
+type differ struct {
+	a     []int
+	b     []int
+	lines []string
+	edits []edit
+}
+
+// Returns the shortest edit script turning a into b, using the linear space variant of the Myers algorithm
+func compute(a []string, b []string) []edit {
+	d := &differ{}
+	ids := make(map[string]int)
+	identify := func(lines []string) []int {
+		numbers := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(d.lines)
+				ids[line] = id
+				d.lines = append(d.lines, line)
+			}
+			numbers[i] = id
+		}
+		return numbers
+	}
+	d.a, d.b = identify(a), identify(b)
+	d.diff(0, len(a), 0, len(b))
+	return d.edits
+}
+
+// Helper function to append the edits turning a[aLo:aHi] into b[bLo:bHi].
+// Common prefixes and suffixes are skipped, and the rest is split around its middle snake.
+func (d *differ) diff(aLo int, aHi int, bLo int, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.edits = append(d.edits, edit{equal, d.lines[d.a[aLo]], aLo, bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-1-suffix] == d.b[bHi-1-suffix] {
+		suffix++
+	}
+	aEnd, bEnd := aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aEnd:
+		for y := bLo; y < bEnd; y++ {
+			d.edits = append(d.edits, edit{insert, d.lines[d.b[y]], aLo, y})
+		}
+	case bLo == bEnd:
+		for x := aLo; x < aEnd; x++ {
+			d.edits = append(d.edits, edit{remove, d.lines[d.a[x]], x, bLo})
+		}
+	default:
+		// both parts are at least two edits apart, so each half is shorter
+		x, y, u, v := d.middleSnake(aLo, aEnd, bLo, bEnd)
+		d.diff(aLo, x, bLo, y)
+		for i := 0; i < u-x; i++ {
+			d.edits = append(d.edits, edit{equal, d.lines[d.a[x+i]], x + i, y + i})
+		}
+		d.diff(u, aEnd, v, bEnd)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.edits = append(d.edits, edit{equal, d.lines[d.a[aEnd+i]], aEnd + i, bEnd + i})
+	}
+}
+
+// Returns the middle snake of the shortest edit script turning a[aLo:aHi] into b[bLo:bHi], from (x, y) to (u, v).
+// The script is searched from both ends at once until the paths overlap.
+func (d *differ) middleSnake(aLo int, aHi int, bLo int, bHi int) (int, int, int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	offset := max + 1
+	// furthest x reached on each diagonal, from the start and from the end
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for e := 0; e <= max; e++ {
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || (k != e && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			// diagonal k is diagonal delta-k from the end
+			if c := delta - k; odd && c >= -(e-1) && c <= e-1 && x+backward[offset+c] >= n {
+				return aLo + x0, bLo + y0, aLo + x, bLo + y
+			}
+		}
+		for c := -e; c <= e; c += 2 {
+			var x int
+			if c == -e || (c != e && backward[offset+c-1] < backward[offset+c+1]) {
+				x = backward[offset+c+1]
+			} else {
+				x = backward[offset+c-1] + 1
+			}
+			y := x - c
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+c] = x
+			if k := delta - c; !odd && k >= -e && k <= e && x+forward[offset+k] >= n {
+				return aHi - x, bHi - y, aHi - x0, bHi - y0
+			}
+		}
+	}
+	// unreachable, as the paths always overlap within max steps
+	return aLo, bLo, aLo, bLo
+}
+
+// Helper function to format the range of a hunk
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// Returns the unified diff between two texts, or an empty string if they are equal
+func Unified(oldName string, newName string, old string, new string) string {
+	if old == new {
+		return ""
+	}
+	edits := compute(splitLines(old), splitLines(new))
+
+	// keep every edit within the context of a change
+	included := make([]bool, len(edits))
+	for i, e := range edits {
+		if e.op == equal {
+			continue
+		}
+		for j := i - contextLines; j <= i+contextLines; j++ {
+			if j >= 0 && j < len(edits) {
+				included[j] = true
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("--- " + oldName + "\n")
+	sb.WriteString("+++ " + newName + "\n")
+	for start := 0; start < len(edits); start++ {
+		if !included[start] {
+			continue
+		}
+		end := start
+		oldCount, newCount := 0, 0
+		for end < len(edits) && included[end] {
+			switch edits[end].op {
+			case equal:
+				oldCount++
+				newCount++
+			case remove:
+				oldCount++
+			case insert:
+				newCount++
+			}
+			end++
+		}
+
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(edits[start].left, oldCount), hunkRange(edits[start].right, newCount)))
+		for _, e := range edits[start:end] {
+			switch e.op {
+			case equal:
+				sb.WriteString(" ")
+			case remove:
+				sb.WriteString("-")
+			case insert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = end
+	}
+	return sb.String()
+}
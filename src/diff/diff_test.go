@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"empty", "", "", ""},
+		{"added", "", "a\n", "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n"},
+		{"removed", "a\n", "", "--- a\n+++ b\n@@ -1 +0,0 @@\n-a\n"},
+		{"changed", "a\nb\nc\n", "a\nx\nc\n", "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		{"no newline", "a\n", "a", "--- a\n+++ b\n@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n"},
+		{
+			"hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"x\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ny\n",
+			"--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+y\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.old, tt.new); got != tt.want {
+				t.Errorf("Unified() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Helper function returning the length of the longest common subsequence of a and b
+func lcs(a []string, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] > lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	return lengths[0][0]
+}
+
+func TestComputeShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		edits := compute(a, b)
+
+		var left, right []string
+		changes := 0
+		for j, e := range edits {
+			switch e.op {
+			case equal:
+				if e.left != len(left) || e.right != len(right) {
+					t.Fatalf("edit %d of %q -> %q is at %d,%d, want %d,%d", j, a, b, e.left, e.right, len(left), len(right))
+				}
+				left = append(left, e.line)
+				right = append(right, e.line)
+			case remove:
+				left = append(left, e.line)
+				changes++
+			case insert:
+				right = append(right, e.line)
+				changes++
+			}
+		}
+		if strings.Join(left, "") != strings.Join(a, "") || strings.Join(right, "") != strings.Join(b, "") {
+			t.Fatalf("edits of %q -> %q give %q -> %q", a, b, left, right)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); changes != want {
+			t.Fatalf("edits of %q -> %q have %d changes, want %d", a, b, changes, want)
+		}
+	}
+}
+
+func TestComputeLarge(t *testing.T) {
+	a := make([]string, 6000)
+	b := make([]string, 6000)
+	for i := range a {
+		a[i] = "a" + strings.Repeat("x", i%7) + "\n"
+		b[i] = "b" + strings.Repeat("x", i%7) + "\n"
+	}
+	if edits := compute(a, b); len(edits) != 12000 {
+		t.Errorf("compute() returned %d edits, want 12000", len(edits))
+	}
+}
@@ -0,0 +1,176 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+)
+
+// Hue rotation between the colors derived for the capture groups, in degrees
+const highlightHueStep = 47
+
+// Helper function returning the color of the highlights of the given class.
+// Matches (class 0) take the keyword color, and capture groups the other colors of the theme,
+// then colors derived from them by rotating their hue, so that no group takes the color of the matches.
+func (ui *UI) highlightColor(class int) tcell.Color {
+	if class <= 0 {
+		return ui.Theme.KeywordColor
+	}
+	colors := []tcell.Color{ui.Theme.TextColor, ui.Theme.TitleColor, ui.Theme.ErrorColor}
+	color := colors[(class-1)%len(colors)]
+	degrees := (class - 1) / len(colors) * highlightHueStep
+	for {
+		derived := color
+		if degrees > 0 {
+			derived = rotateHue(color, float64(degrees))
+		}
+		if derived != ui.Theme.KeywordColor {
+			return derived
+		}
+		degrees += highlightHueStep
+	}
+}
+
+// Helper function returning the given color with its hue rotated by the given number of degrees.
+// Grays are given some saturation, so that their hue shows.
+func rotateHue(color tcell.Color, degrees float64) tcell.Color {
+	red, green, blue := color.RGB()
+	if red < 0 {
+		red, green, blue = 0xc0, 0xc0, 0xc0
+	}
+	r, g, b := float64(red)/255, float64(green)/255, float64(blue)/255
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+
+	// conversion to HSV
+	hue, saturation, value := 0.0, 0.0, max
+	if max > 0 {
+		saturation = (max - min) / max
+	}
+	switch delta := max - min; {
+	case delta == 0:
+	case max == r:
+		hue = 60 * math.Mod((g-b)/delta, 6)
+	case max == g:
+		hue = 60 * ((b-r)/delta + 2)
+	default:
+		hue = 60 * ((r-g)/delta + 4)
+	}
+	hue = math.Mod(hue+degrees+360, 360)
+	saturation = math.Max(saturation, 0.5)
+	value = math.Max(value, 0.5)
+
+	// conversion back to RGB
+	chroma := value * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	var rgb [3]float64
+	switch {
+	case hue < 60:
+		rgb = [3]float64{chroma, x, 0}
+	case hue < 120:
+		rgb = [3]float64{x, chroma, 0}
+	case hue < 180:
+		rgb = [3]float64{0, chroma, x}
+	case hue < 240:
+		rgb = [3]float64{0, x, chroma}
+	case hue < 300:
+		rgb = [3]float64{x, 0, chroma}
+	default:
+		rgb = [3]float64{chroma, 0, x}
+	}
+	m := value - chroma
+	return tcell.NewRGBColor(int32(math.Round((rgb[0]+m)*255)), int32(math.Round((rgb[1]+m)*255)), int32(math.Round((rgb[2]+m)*255)))
+}
+
+// Helper function to apply the given highlights to the given text, underlining them if asked.
+// Each byte takes the color of the last highlight it belongs to.
+func (ui *UI) highlight(text string, highlights []program.Highlight, underline bool) string {
+	if len(highlights) == 0 {
+		return tview.Escape(text)
+	}
+	classes := make([]int, len(text))
+	for i := range classes {
+		classes[i] = -1
+	}
+	for _, h := range highlights {
+		for i := h.Start; i < h.End && i < len(text); i++ {
+			if i >= 0 {
+				classes[i] = h.Class
+			}
+		}
+	}
+
+	style := ""
+	if underline {
+		style = "::u"
+	}
+	var sb strings.Builder
+	for start := 0; start < len(text); {
+		end := start
+		for end < len(text) && classes[end] == classes[start] {
+			end++
+		}
+		if classes[start] < 0 {
+			sb.WriteString(tview.Escape(text[start:end]))
+		} else {
+			sb.WriteString(fmt.Sprintf("[#%06x%s]", ui.highlightColor(classes[start]).Hex(), style))
+			sb.WriteString(tview.Escape(text[start:end]))
+			sb.WriteString("[-::-]")
+		}
+		start = end
+	}
+	return sb.String()
+}
+
+// Helper function returning the completions of the option being typed, as the whole text of OptionsInput
+func (ui *UI) completeOption(text string) []string {
+	ui.completing = false
+	s := ui.stages[ui.selectedStage]
+	c, ok := s.program.Backend().(program.Completer)
+	if !ok || ui.loadingStage {
+		return nil
+	}
+	i := strings.LastIndexAny(text, " \t") + 1
+	prefix := text[i:]
+	if !strings.HasPrefix(prefix, "-") {
+		return nil
+	}
+
+	var entries []string
+	for _, option := range c.CompleteOption(s.command(ui.environment), prefix) {
+		if option != prefix {
+			entries = append(entries, text[:i]+option)
+		}
+	}
+	ui.completing = len(entries) > 0
+	return entries
+}
+
+// Helper function to use the completion selected in the drop-down of OptionsInput
+func (ui *UI) completedOption(text string, index int, source int) bool {
+	if source == tview.AutocompletedNavigate {
+		return false
+	}
+	ui.completing = false
+	ui.OptionsInput.SetText(text)
+	return true
+}
+
+// Helper function returning whether the given key is meant for the completions of OptionsInput
+func (ui *UI) completingOption(key tcell.Key) bool {
+	if !ui.completing {
+		return false
+	}
+	switch key {
+	case tcell.KeyEsc:
+		ui.completing = false
+		return true
+	case tcell.KeyTab, tcell.KeyEnter, tcell.KeyUp, tcell.KeyDown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+)
+
+// Name of the file to which benchmarks are exported, followed by a number if it already exists
+const benchmarkExportName = "play-benchmark"
+
+// Benchmark of the current pipeline, and of the baseline if one is pinned, as exported
+type benchmarkExport struct {
+	Current  program.Benchmark  `json:"current"`
+	Baseline *program.Benchmark `json:"baseline,omitempty"`
+}
+
+// Returns the TextView used for benchmarks
+func benchmarkView() *tview.TextView {
+	t := tview.NewTextView().
+		SetDynamicColors(true)
+	t.SetBorder(true)
+	t.SetTitle(" Benchmark ")
+	return t
+}
+
+// Helper function to pin the current pipeline as the baseline of benchmarks
+func (ui *UI) pinBaseline() {
+	ui.baseline = ui.buildPipeline()
+	ui.updatePipelineText()
+}
+
+// Helper function to format a number of bytes
+func formatSize(size int64) string {
+	if size <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+}
+
+// Helper function for benchmarking the current pipeline against the baseline, if any.
+// The benchmark runs in the background and is cancelled when its view is closed.
+func (ui *UI) runBenchmark() {
+	pipelines := [][]program.Command{ui.buildPipeline()}
+	if ui.baseline != nil {
+		pipelines = append(pipelines, ui.baseline)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.benchmarkCancel = cancel
+	ui.benchmarks = nil
+	ui.BenchmarkView.SetTitle(fmt.Sprintf(" Benchmark (running %d runs after %d warmup runs, Esc to cancel) ", ui.settings.BenchmarkRuns, ui.settings.BenchmarkWarmup))
+	ui.BenchmarkView.SetTitleColor(ui.Theme.KeywordColor)
+	ui.BenchmarkView.SetText("")
+	ui.benchmarkFocus = ui.App.GetFocus()
+	ui.App.SetRoot(ui.BenchmarkView, true).
+		SetFocus(ui.BenchmarkView)
+
+	go func() {
+		benchmarks, err := program.RunBenchmark(ctx, pipelines, ui.settings, ui.settings.BenchmarkRuns, ui.settings.BenchmarkWarmup)
+		if ctx.Err() != nil {
+			return
+		}
+		ui.App.QueueUpdateDraw(func() {
+			ui.showBenchmarks(benchmarks, err)
+		})
+	}()
+}
+
+// Helper function for displaying the benchmarks of the current pipeline and of the baseline side by side
+func (ui *UI) showBenchmarks(benchmarks []program.Benchmark, err error) {
+	if err != nil {
+		ui.BenchmarkView.SetTitle(" Benchmark (failed) ")
+		ui.BenchmarkView.SetTitleColor(ui.Theme.ErrorColor)
+		ui.BenchmarkView.SetText(colorTag(ui.Theme.ErrorColor) + tview.Escape(err.Error()))
+		return
+	}
+	ui.benchmarks = benchmarks
+
+	names := []string{"current", "baseline"}[:len(benchmarks)]
+	var sb strings.Builder
+	for i, b := range benchmarks {
+		sb.WriteString(fmt.Sprintf("%s%-10s%s%s\n", colorTag(ui.Theme.KeywordColor), names[i], colorTag(ui.Theme.TextColor), tview.Escape(b.Pipeline)))
+	}
+	sb.WriteString("\n" + colorTag(ui.Theme.KeywordColor) + fmt.Sprintf("%-10s", ""))
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("%-14s", name))
+	}
+	rows := []struct {
+		name  string
+		value func(b program.Benchmark) string
+	}{
+		{"mean", func(b program.Benchmark) string { return formatDuration(b.Mean) }},
+		{"median", func(b program.Benchmark) string { return formatDuration(b.Median) }},
+		{"p95", func(b program.Benchmark) string { return formatDuration(b.P95) }},
+		{"peak RSS", func(b program.Benchmark) string { return formatSize(b.PeakRSS) }},
+	}
+	for _, row := range rows {
+		sb.WriteString(fmt.Sprintf("\n%s%-10s%s", colorTag(ui.Theme.KeywordColor), row.name, colorTag(ui.Theme.TextColor)))
+		for _, b := range benchmarks {
+			sb.WriteString(fmt.Sprintf("%-14s", row.value(b)))
+		}
+	}
+
+	if len(benchmarks) == 2 && benchmarks[0].Median > 0 && benchmarks[1].Median > 0 {
+		ratio := float64(benchmarks[1].Median) / float64(benchmarks[0].Median)
+		comparison := "faster"
+		if ratio < 1 {
+			ratio = 1 / ratio
+			comparison = "slower"
+		}
+		sb.WriteString(fmt.Sprintf("\n\n%scurrent is %.2fx %s than baseline (median)", colorTag(ui.Theme.TextColor), ratio, comparison))
+	}
+
+	ui.BenchmarkView.SetTitle(fmt.Sprintf(" Benchmark (%d runs after %d warmup runs, e to export as JSON) ", benchmarks[0].Runs, benchmarks[0].Warmup))
+	ui.BenchmarkView.SetText(sb.String())
+	ui.BenchmarkView.ScrollToBeginning()
+}
+
+// Helper function to export the last benchmarks as JSON
+func (ui *UI) exportBenchmarks() {
+	if ui.benchmarks == nil {
+		return
+	}
+	export := benchmarkExport{Current: ui.benchmarks[0]}
+	if len(ui.benchmarks) > 1 {
+		export.Baseline = &ui.benchmarks[1]
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	path := ""
+	if err == nil {
+		path, err = writeNewFile(benchmarkExportName, ".json", append(data, '\n'))
+	}
+	if err != nil {
+		ui.BenchmarkView.SetTitle(" Benchmark (not exported: " + err.Error() + ") ")
+		ui.BenchmarkView.SetTitleColor(ui.Theme.ErrorColor)
+		return
+	}
+	ui.BenchmarkView.SetTitle(" Benchmark (exported to " + path + ") ")
+}
+
+// Helper function to write the given data to a new file of the current directory, never overwriting an existing one.
+// The file is named after the given name and extension, with a number added to the name if needed, and its path is returned.
+func writeNewFile(name string, extension string, data []byte) (string, error) {
+	for i := 1; ; i++ {
+		path := name + extension
+		if i > 1 {
+			path = fmt.Sprintf("%s-%d%s", name, i, extension)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return path, err
+	}
+}
+
+// Helper function to close the benchmark, cancelling it if still running
+func (ui *UI) closeBenchmark() {
+	if ui.benchmarkCancel != nil {
+		ui.benchmarkCancel()
+		ui.benchmarkCancel = nil
+	}
+	ui.App.SetRoot(ui.Flex, true).
+		SetFocus(ui.benchmarkFocus)
+}
+
+// Function for configuring BenchmarkView TextView
+func (ui *UI) configBenchmarkView() {
+	ui.BenchmarkView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEsc:
+			ui.closeBenchmark()
+			return nil
+		case event.Key() == tcell.KeyRune && event.Rune() == 'e':
+			ui.exportBenchmarks()
+			return nil
+		}
+		return event
+	})
+
+	ui.BenchmarkView.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.BenchmarkView.SetTitleColor(ui.Theme.KeywordColor)
+	ui.BenchmarkView.SetBorderColor(ui.Theme.BorderColor)
+	ui.BenchmarkView.SetTextColor(ui.Theme.BorderColor)
+}
@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/paololazzari/play/src/diff"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+)
+
+// Helper function returning the pipeline using the compared implementation
+// in place of the one of the session
+func (ui *UI) alternativePipeline(commands []program.Command) []program.Command {
+	alternative := make([]program.Command, len(commands))
+	for i, command := range commands {
+		if command.Program == ui.compare.Name {
+			command.Path = ui.compare.Path
+			command.PathArgs = ui.compare.PathArgs
+			command.Engine = ui.compare.Engine
+		}
+		alternative[i] = command
+	}
+	return alternative
+}
+
+// Helper function for evaluating the pipeline with both implementations concurrently
+func (ui *UI) scheduleComparison(commands []program.Command) {
+	pipelines := [][]program.Command{commands, ui.alternativePipeline(commands)}
+	names := [2]string{ui.stages[0].program.DisplayName(), ui.compare.DisplayName()}
+	ui.scheduler.schedule(func(ctx context.Context, seq uint64) {
+		// as for a single pipeline, invalid expressions of either implementation are not evaluated
+		for _, commands := range pipelines {
+			if stage, d := program.ValidatePipeline(ctx, commands, ui.settings); d != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				ui.App.QueueUpdateDraw(func() {
+					if ui.scheduler.isLatest(seq) {
+						ui.showDiagnostic(stage, d)
+					}
+				})
+				return
+			}
+		}
+		results, errs := program.RunPipelines(ctx, pipelines, ui.settings)
+		if ctx.Err() != nil {
+			return
+		}
+		// the differences are computed here rather than on the UI goroutine, as outputs may be large
+		comparison := compareResults(results, errs, len(commands), names)
+		ui.App.QueueUpdateDraw(func() {
+			// discard results of evaluations superseded in the meantime
+			if ui.scheduler.isLatest(seq) {
+				ui.showDiagnostic(-1, nil)
+				ui.showResults(results[0], errs[0])
+				ui.comparison = comparison
+				ui.showComparison()
+			}
+		})
+	})
+}
+
+// Results of both implementations at a stage of the pipeline, as text, and their differences
+type stageComparison struct {
+	texts [2]string
+	diff  string
+}
+
+// Returns the comparison of the results of both implementations at each of the given number of stages
+func compareResults(results [][]program.Result, errs []error, stages int, names [2]string) []stageComparison {
+	comparison := make([]stageComparison, stages)
+	for stage := range comparison {
+		c := &comparison[stage]
+		for i := range c.texts {
+			if errs[i] == nil && stage < len(results[i]) {
+				c.texts[i] = transcript(results[i][stage], nil)
+			} else {
+				c.texts[i] = transcript(program.Result{}, errs[i])
+			}
+		}
+		c.diff = diff.Unified(names[0], names[1], c.texts[0], c.texts[1])
+	}
+	return comparison
+}
+
+// Helper function to describe a result as text, so that results can be compared line by line
+func transcript(res program.Result, err error) string {
+	if err != nil {
+		return "error: " + err.Error() + "\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(res.Stdout)
+	if len(res.Stdout) > 0 && !strings.HasSuffix(res.Stdout, "\n") {
+		sb.WriteString("\n")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(res.Stderr, "\n"), "\n") {
+		if len(line) > 0 {
+			sb.WriteString("stderr: " + line + "\n")
+		}
+	}
+	sb.WriteString(res.Status() + "\n")
+	return sb.String()
+}
+
+// Helper function for displaying the differences between the results of both implementations
+func (ui *UI) showComparison() {
+	if ui.selectedStage >= len(ui.comparison) {
+		return
+	}
+	c := ui.comparison[ui.selectedStage]
+	alternativeName := ui.compare.DisplayName()
+	d := c.diff
+	if len(d) == 0 {
+		ui.CompareView.SetTitle(fmt.Sprintf(" Comparison with %s (identical) ", alternativeName))
+		ui.CompareView.SetTitleColor(ui.Theme.KeywordColor)
+		ui.CompareView.SetText(tview.Escape(c.texts[1]))
+		return
+	}
+	ui.CompareView.SetTitle(fmt.Sprintf(" Comparison with %s (different) ", alternativeName))
+	ui.CompareView.SetTitleColor(ui.Theme.ErrorColor)
+	ui.CompareView.SetText(ui.colorizeDiff(d))
+	ui.CompareView.ScrollToBeginning()
+}
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Valid environment variable name
+var environmentVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Returns the TextArea for the environment variables
+func environmentInput() *tview.TextArea {
+	t := tview.NewTextArea().
+		SetPlaceholder("NAME=value, one per line")
+	t.SetBorder(true)
+	t.SetTitle(" Environment ")
+	return t
+}
+
+// Returns the Flex used for the environment variables
+func environmentFlex() *tview.Flex {
+	return tview.NewFlex()
+}
+
+// Helper function to parse the environment variables, one NAME=value per line.
+// Empty lines and lines starting with # are ignored, and values are kept as is, including trailing spaces.
+func parseEnvironment(text string) ([]string, error) {
+	var env []string
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimLeft(line, " \t")
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, _, found := strings.Cut(line, "=")
+		if !found || !environmentVariableName.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable on line %d", i+1)
+		}
+		env = append(env, line)
+	}
+	return env, nil
+}
+
+// Helper function to show the active environment variables in the command bar
+func (ui *UI) updateCommandText() {
+	var sb strings.Builder
+	sb.WriteString(" > ")
+	for _, variable := range ui.environment {
+		sb.WriteString(variable)
+		sb.WriteString(" ")
+	}
+	sb.WriteString(ui.Label)
+	ui.CommandText.SetText(sb.String())
+	ui.ChildFlex.ResizeItem(ui.CommandText, len(sb.String())+1, 1)
+}
+
+// Helper function to show or hide the environment variables
+func (ui *UI) toggleEnvironment() {
+	if ui.App.GetFocus() == ui.EnvironmentInput {
+		ui.App.SetRoot(*ui.ActiveFlex, true)
+		if ui.ActiveFlex == &ui.Flex {
+			ui.focusCommandBar()
+		} else {
+			ui.App.SetFocus(ui.ArgumentsInputWide)
+		}
+		return
+	}
+	ui.App.SetRoot(ui.EnvironmentFlex, true).
+		SetFocus(ui.EnvironmentInput)
+}
+
+// Function for configuring EnvironmentInput TextArea
+func (ui *UI) configEnvironmentInput() {
+	ui.EnvironmentInput.SetChangedFunc(func() {
+		env, err := parseEnvironment(ui.EnvironmentInput.GetText())
+		if err != nil {
+			ui.EnvironmentInput.SetTitle(fmt.Sprintf(" Environment (%s) ", err))
+			ui.EnvironmentInput.SetTitleColor(ui.Theme.ErrorColor)
+			return
+		}
+		ui.EnvironmentInput.SetTitle(" Environment ")
+		ui.EnvironmentInput.SetTitleColor(ui.Theme.KeywordColor)
+		ui.environment = env
+		ui.updateCommandText()
+		ui.scheduleEvaluation()
+	})
+
+	ui.EnvironmentInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			ui.toggleEnvironment()
+			return nil
+		}
+		return event
+	})
+
+	ui.EnvironmentInput.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.EnvironmentInput.SetTitleColor(ui.Theme.KeywordColor)
+	ui.EnvironmentInput.SetBorderColor(ui.Theme.BorderColor)
+	ui.EnvironmentInput.SetTextStyle(tcell.StyleDefault.Foreground(ui.Theme.TextColor).Background(ui.Theme.BackGroundColor))
+	ui.EnvironmentInput.SetPlaceholderStyle(tcell.StyleDefault.Foreground(ui.Theme.TextColor).Background(ui.Theme.BackGroundColor))
+}
+
+// Function for configuring EnvironmentFlex Flex
+func (ui *UI) configEnvironmentFlex() {
+	ui.EnvironmentFlex.SetDirection(tview.FlexRow).
+		AddItem(ui.EnvironmentInput, 0, 1, false).
+		AddItem(ui.OutputView, 0, 2, false)
+	ui.EnvironmentFlex.SetBorder(true)
+	ui.EnvironmentFlex.SetTitle(" play ")
+	ui.EnvironmentFlex.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.EnvironmentFlex.SetTitleColor(ui.Theme.TitleColor)
+	ui.EnvironmentFlex.SetBorderColor(ui.Theme.BorderColor)
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+)
+
+// Returns the TextView used for the help of programs
+func helpView() *tview.TextView {
+	t := tview.NewTextView().
+		SetDynamicColors(true)
+	t.SetBorder(true)
+	t.SetTitle(" Help ")
+	return t
+}
+
+// Helper function for displaying the help of the program of the selected stage.
+// The help is loaded in the background and its loading is cancelled when its view is closed.
+func (ui *UI) showHelp() {
+	p := ui.stages[ui.selectedStage].program
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.helpCancel = cancel
+
+	if len(p.Engine) > 0 {
+		ui.HelpView.SetTitle(fmt.Sprintf(" Help of %s ", p.DisplayName()))
+	} else {
+		ui.HelpView.SetTitle(fmt.Sprintf(" Help of %s (%s) ", p.DisplayName(), p.HelpFlag))
+	}
+	ui.HelpView.SetTitleColor(ui.Theme.KeywordColor)
+	ui.HelpView.SetText("loading...")
+	ui.helpFocus = ui.App.GetFocus()
+	ui.App.SetRoot(ui.HelpView, true).
+		SetFocus(ui.HelpView)
+
+	settings := ui.settings
+	go func() {
+		res, err := program.Help(ctx, p, settings)
+		if ctx.Err() != nil {
+			return
+		}
+		ui.App.QueueUpdateDraw(func() {
+			// the help may have been closed in the meantime
+			if ctx.Err() != nil {
+				return
+			}
+			ui.showHelpResult(res, err)
+		})
+	}()
+}
+
+// Helper function for displaying the help of a program once loaded
+func (ui *UI) showHelpResult(res program.Result, err error) {
+	switch {
+	case err != nil:
+		ui.HelpView.SetText(colorTag(ui.Theme.ErrorColor) + tview.Escape(err.Error()))
+	case len(res.Stdout) == 0:
+		// some programs print their help on standard error
+		ui.HelpView.SetText(tview.Escape(res.Stderr))
+	default:
+		ui.HelpView.SetText(tview.Escape(res.Stdout))
+	}
+	ui.HelpView.ScrollToBeginning()
+}
+
+// Helper function to close the help, cancelling its loading if still running
+func (ui *UI) closeHelp() {
+	if ui.helpCancel != nil {
+		ui.helpCancel()
+		ui.helpCancel = nil
+	}
+	ui.App.SetRoot(ui.Flex, true).
+		SetFocus(ui.helpFocus)
+}
+
+// Function for configuring HelpView TextView
+func (ui *UI) configHelpView() {
+	ui.HelpView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc || event.Key() == tcell.KeyF1 {
+			ui.closeHelp()
+			return nil
+		}
+		return event
+	})
+
+	ui.HelpView.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.HelpView.SetBorderColor(ui.Theme.BorderColor)
+	ui.HelpView.SetTextColor(ui.Theme.BorderColor)
+}
@@ -0,0 +1,257 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+	"golang.org/x/exp/slices"
+)
+
+// Stage of the pipeline
+type stage struct {
+	program     program.Program
+	options     string
+	arguments   string
+	expressions []string
+	quote       string
+}
+
+// Stage constructor
+func newStage(p program.Program) *stage {
+	return &stage{
+		program: p,
+		options: p.Options,
+		quote:   p.Quote,
+	}
+}
+
+// Returns the command of the stage
+func (s *stage) command(env []string) program.Command {
+	return program.Command{
+		Program:          s.program.Name,
+		Path:             s.program.Path,
+		PathArgs:         s.program.PathArgs,
+		Options:          strings.TrimSpace(s.options),
+		Expression:       s.arguments,
+		ExtraExpressions: s.expressions,
+		Quote:            s.quote,
+		FilePlacement:    s.program.FilePlacement,
+		EndOfOptions:     s.program.RespectsEndOfOptions,
+		Env:              env,
+		Template:         s.program.Template,
+		Engine:           s.program.Engine,
+	}
+}
+
+// Returns the Flex holding the pipeline overview
+func pipelineFlex() *tview.Flex {
+	return tview.NewFlex()
+}
+
+// Returns the TextView with the pipeline overview
+func pipelineText() *tview.TextView {
+	return tview.NewTextView().
+		SetDynamicColors(true)
+}
+
+// Returns the InputField used to add a stage to the pipeline
+func stageInput() *tview.InputField {
+	return tview.NewInputField().
+		SetLabel(" | ").
+		SetPlaceholder("<program of the new stage>").
+		SetPlaceholderStyle(tcell.StyleDefault)
+}
+
+// Helper function to store the content of the command bar in the selected stage
+func (ui *UI) saveStage() {
+	s := ui.stages[ui.selectedStage]
+	s.options = ui.OptionsInput.GetText()
+	s.arguments = ui.getActiveInputText()
+	s.quote = ui.OpeningQuoteText.GetText(false)
+	s.expressions = nil
+	for _, input := range ui.ExpressionInputs {
+		s.expressions = append(s.expressions, input.GetText())
+	}
+}
+
+// Helper function to show the given stage in the command bar
+func (ui *UI) loadStage(i int) {
+	// the command bar is inconsistent until every field has been loaded
+	ui.loadingStage = true
+	defer func() { ui.loadingStage = false }()
+
+	ui.selectedStage = i
+	s := ui.stages[i]
+	ui.Label = s.program.DisplayName()
+	ui.EndOfOptionsSeparator = s.program.RespectsEndOfOptions
+	ui.layoutChildFlex()
+	ui.updateFileOptionsText()
+
+	ui.OptionsInput.SetText(s.options)
+	ui.ArgumentsInput.SetText(s.arguments)
+	for j, input := range ui.ExpressionInputs {
+		if j < len(s.expressions) {
+			input.SetText(s.expressions[j])
+		} else {
+			input.SetText("")
+		}
+	}
+	ui.resizeChildFlexIfNeeded()
+	ui.updatePipelineText()
+	if i < len(ui.results) {
+		ui.showResult(ui.results[i])
+	}
+	if ui.comparison != nil {
+		ui.showComparison()
+	}
+}
+
+// Helper function to select the given stage
+func (ui *UI) selectStage(i int) {
+	ui.saveStage()
+	ui.loadStage(i)
+}
+
+// Helper function to add a stage running the given program after the selected one
+func (ui *UI) addStage(name string) error {
+	p := program.Lookup(name)
+	// plugins are evaluated by their own backend
+	if _, err := exec.LookPath(p.Executable()); len(p.Engine) == 0 && (err != nil || program.BuiltinOnly(name)) {
+		// like with the auto engine, programs which are not found fall back to their builtin engine
+		if p, err = p.WithBuiltinEngine(); err != nil {
+			return fmt.Errorf("%s not found", name)
+		}
+	}
+	ui.saveStage()
+	ui.stages = slices.Insert(ui.stages, ui.selectedStage+1, newStage(p))
+	ui.results = nil
+	ui.comparison = nil
+	ui.loadStage(ui.selectedStage + 1)
+	ui.scheduleEvaluation()
+	return nil
+}
+
+// Helper function to remove the selected stage
+func (ui *UI) removeStage() {
+	if len(ui.stages) == 1 {
+		return
+	}
+	ui.stages = slices.Delete(ui.stages, ui.selectedStage, ui.selectedStage+1)
+	ui.results = nil
+	ui.comparison = nil
+	if ui.selectedStage > 0 {
+		ui.loadStage(ui.selectedStage - 1)
+	} else {
+		ui.loadStage(0)
+	}
+	ui.scheduleEvaluation()
+}
+
+// Helper function to build the commands of the pipeline
+func (ui *UI) buildPipeline() []program.Command {
+	ui.saveStage()
+	ui.updatePipelineText()
+
+	commands := make([]program.Command, len(ui.stages))
+	for i, s := range ui.stages {
+		commands[i] = s.command(ui.environment)
+	}
+	// the commands are evaluated in the background while the selected files may change in place
+	commands[0].Files = append([]string(nil), ui.FileOptionsInputSlice...)
+	if len(ui.stdinTmpFile) > 0 && len(ui.FileOptionsInputSlice) == 0 {
+		commands[0].Files = []string{ui.stdinTmpFile}
+	}
+	return commands
+}
+
+// Helper function to show the overview of the pipeline, highlighting the selected stage,
+// followed by the baseline of benchmarks if one is pinned
+func (ui *UI) updatePipelineText() {
+	var sb strings.Builder
+	if len(ui.stages) > 1 {
+		sb.WriteString(" ")
+		for i, s := range ui.stages {
+			if i > 0 {
+				sb.WriteString(colorTag(ui.Theme.TitleColor))
+				sb.WriteString(" | ")
+			}
+			if i == ui.selectedStage {
+				sb.WriteString(colorTag(ui.Theme.KeywordColor))
+			} else {
+				sb.WriteString(colorTag(ui.Theme.TextColor))
+			}
+			sb.WriteString(tview.Escape(s.command(nil).String()))
+		}
+	}
+	if ui.baseline != nil {
+		sb.WriteString(colorTag(ui.Theme.TitleColor))
+		sb.WriteString("  baseline: ")
+		sb.WriteString(colorTag(ui.Theme.TextColor))
+		sb.WriteString(tview.Escape(program.PipelineString(ui.baseline)))
+	}
+	ui.PipelineText.SetText(sb.String())
+}
+
+// Helper function to show or hide the input of a new stage
+func (ui *UI) toggleStageInput(show bool) {
+	ui.PipelineFlex.Clear()
+	if show {
+		ui.StageInput.SetText("")
+		ui.PipelineFlex.AddItem(ui.StageInput, 0, 1, true)
+		ui.App.SetFocus(ui.StageInput)
+	} else {
+		ui.PipelineFlex.AddItem(ui.PipelineText, 0, 1, false)
+		ui.focusCommandBar()
+	}
+}
+
+// Helper function to tell whether the stages can be edited from the focused component
+func (ui *UI) canEditStages() bool {
+	if ui.ActiveFlex != &ui.Flex {
+		return false
+	}
+	focus := ui.App.GetFocus()
+	return focus == ui.OutputView || ui.fieldIndex(focus) >= 0
+}
+
+// Function for configuring StageInput InputField
+func (ui *UI) configStageInput() {
+	ui.StageInput.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			name := strings.TrimSpace(ui.StageInput.GetText())
+			if len(name) == 0 {
+				return
+			}
+			if err := ui.addStage(name); err != nil {
+				ui.StageInput.SetLabel(" | " + err.Error() + ", program: ")
+				ui.StageInput.SetLabelColor(ui.Theme.ErrorColor)
+				return
+			}
+			ui.toggleStageInput(false)
+		case tcell.KeyEsc:
+			ui.toggleStageInput(false)
+		}
+	})
+	ui.StageInput.SetChangedFunc(func(text string) {
+		ui.StageInput.SetLabel(" | ")
+		ui.StageInput.SetLabelColor(ui.Theme.TitleColor)
+	})
+
+	ui.StageInput.SetLabelColor(ui.Theme.TitleColor)
+	ui.StageInput.SetFieldTextColor(ui.Theme.TextColor)
+	ui.StageInput.SetFieldBackgroundColor(ui.Theme.BackGroundColor)
+	ui.StageInput.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.StageInput.SetPlaceholderTextColor(ui.Theme.TextColor)
+}
+
+// Function for configuring PipelineFlex Flex
+func (ui *UI) configPipelineFlex() {
+	ui.PipelineText.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.PipelineFlex.AddItem(ui.PipelineText, 0, 1, false)
+	ui.PipelineFlex.SetBackgroundColor(ui.Theme.BackGroundColor)
+}
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+)
+
+// Returns the TextView used for the matches of regular expressions
+func matchesView() *tview.TextView {
+	t := tview.NewTextView().
+		SetDynamicColors(true)
+	t.SetBorder(true)
+	t.SetTitle(" Matches ")
+	return t
+}
+
+// Helper function returning whether the matches of the expression played with are shown
+func (ui *UI) showsMatches() bool {
+	return ui.stages[0].program.ReportsMatches()
+}
+
+// Helper function for displaying the table of the matches of the expression of the selected stage
+func (ui *UI) showMatches(res program.Result) {
+	if !ui.showsMatches() {
+		return
+	}
+	if !ui.stages[ui.selectedStage].program.ReportsMatches() {
+		ui.MatchesView.SetTitle(" Matches (not available for this stage) ")
+		ui.MatchesView.SetText("")
+		return
+	}
+
+	var sb strings.Builder
+	s := ui.stages[ui.selectedStage]
+	if h, ok := s.program.Backend().(program.Highlighter); ok {
+		// the capture groups of the pattern are shown in the colors of their matches
+		expression := s.arguments
+		sb.WriteString(colorTag(ui.Theme.TitleColor) + "pattern[-] ")
+		sb.WriteString(ui.highlight(expression, h.HighlightExpression(expression), false))
+		sb.WriteString("\n\n")
+	}
+	// the offsets are those of the matches in their file, shown when the input is made of files
+	fileWidth := 0
+	for _, match := range res.Matches {
+		if len(match.File) > fileWidth {
+			fileWidth = len(match.File)
+		}
+	}
+	file := func(name string) string {
+		if fileWidth == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%-*s ", fileWidth, name)
+	}
+	sb.WriteString(colorTag(ui.Theme.TitleColor))
+	sb.WriteString(fmt.Sprintf("%-7s %-6s %-12s %s%-9s %-9s %s[-]\n", "match", "group", "name", file("file"), "start", "end", "text"))
+	row := func(match int, index int, name string, path string, shift int, start int, end int) {
+		text := "(not matched)"
+		if start >= 0 && end <= len(res.Stdout) {
+			text = strconv.Quote(res.Stdout[start:end])
+			start, end = start-shift, end-shift
+		}
+		sb.WriteString(colorTag(ui.highlightColor(index)))
+		sb.WriteString(tview.Escape(fmt.Sprintf("%-7d %-6d %-12s %s%-9d %-9d %s", match, index, name, file(path), start, end, text)))
+		sb.WriteString("[-]\n")
+	}
+	for i, match := range res.Matches {
+		row(i+1, 0, "", match.File, match.Shift, match.Start, match.End)
+		for _, group := range match.Groups {
+			row(i+1, group.Index, group.Name, match.File, match.Shift, group.Start, group.End)
+		}
+	}
+
+	matches := "matches"
+	if len(res.Matches) == 1 {
+		matches = "match"
+	}
+	ui.MatchesView.SetTitle(fmt.Sprintf(" Matches (%d %s) ", len(res.Matches), matches))
+	ui.MatchesView.SetText(sb.String())
+}
+
+// Function for configuring MatchesView TextView
+func (ui *UI) configMatchesView() {
+	ui.MatchesView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc, tcell.KeyTab, tcell.KeyBacktab:
+			ui.App.SetFocus(ui.OutputView)
+		}
+		return event
+	})
+
+	ui.MatchesView.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.MatchesView.SetTitleColor(ui.Theme.KeywordColor)
+	ui.MatchesView.SetBorderColor(ui.Theme.BorderColor)
+	ui.MatchesView.SetTextColor(ui.Theme.BorderColor)
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Time to wait after the last edit before evaluating the expression
+const debounceDelay = 150 * time.Millisecond
+
+// Scheduler for expression evaluations.
+// Every call to schedule supersedes the pending or running evaluation, so
+// that only the result of the latest edit is ever drawn.
+type scheduler struct {
+	mu     sync.Mutex
+	delay  time.Duration
+	seq    uint64
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+// Scheduler constructor
+func newScheduler(delay time.Duration) *scheduler {
+	return &scheduler{
+		delay: delay,
+	}
+}
+
+// Helper function to stop the pending timer and cancel the running evaluation.
+// Must be called with the lock held.
+func (s *scheduler) reset() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// Schedule the given evaluation after the debounce delay.
+// The evaluation runs on its own goroutine and its context is cancelled as
+// soon as a newer evaluation is scheduled.
+func (s *scheduler) schedule(evaluate func(ctx context.Context, seq uint64)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset()
+	s.seq++
+	seq := s.seq
+	s.timer = time.AfterFunc(s.delay, func() {
+		s.mu.Lock()
+		if seq != s.seq {
+			s.mu.Unlock()
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		s.cancel = cancel
+		s.mu.Unlock()
+
+		defer cancel()
+		evaluate(ctx, seq)
+	})
+}
+
+// Returns whether seq belongs to the most recently scheduled evaluation
+func (s *scheduler) isLatest(seq uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return seq == s.seq
+}
+
+// Cancel any pending or running evaluation
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.reset()
+}
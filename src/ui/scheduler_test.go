@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testDelay = 20 * time.Millisecond
+
+func TestSchedulerDebounce(t *testing.T) {
+	s := newScheduler(testDelay)
+	var runs int32
+	done := make(chan uint64, 3)
+	for i := 0; i < 3; i++ {
+		s.schedule(func(ctx context.Context, seq uint64) {
+			atomic.AddInt32(&runs, 1)
+			done <- seq
+		})
+	}
+	select {
+	case seq := <-done:
+		if !s.isLatest(seq) {
+			t.Errorf("evaluation %d is not the latest", seq)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no evaluation ran")
+	}
+	time.Sleep(5 * testDelay)
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("%d evaluations ran, want 1", n)
+	}
+}
+
+func TestSchedulerSupersede(t *testing.T) {
+	s := newScheduler(testDelay)
+	started := make(chan uint64)
+	cancelled := make(chan struct{})
+	s.schedule(func(ctx context.Context, seq uint64) {
+		started <- seq
+		<-ctx.Done()
+		close(cancelled)
+	})
+	var first uint64
+	select {
+	case first = <-started:
+	case <-time.After(time.Second):
+		t.Fatal("the evaluation did not start")
+	}
+
+	latest := make(chan uint64, 1)
+	s.schedule(func(ctx context.Context, seq uint64) {
+		latest <- seq
+	})
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the running evaluation was not cancelled")
+	}
+	if s.isLatest(first) {
+		t.Errorf("superseded evaluation %d is still the latest", first)
+	}
+	select {
+	case seq := <-latest:
+		if !s.isLatest(seq) {
+			t.Errorf("evaluation %d is not the latest", seq)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the newer evaluation did not run")
+	}
+}
+
+func TestSchedulerStop(t *testing.T) {
+	s := newScheduler(testDelay)
+	var runs int32
+	s.schedule(func(ctx context.Context, seq uint64) {
+		atomic.AddInt32(&runs, 1)
+	})
+	s.stop()
+	time.Sleep(5 * testDelay)
+	if n := atomic.LoadInt32(&runs); n != 0 {
+		t.Errorf("%d evaluations ran after stop, want 0", n)
+	}
+}
@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+)
+
+// Interval between the updates of the progress of the standard input while it is read
+const stdinProgressInterval = 200 * time.Millisecond
+
+// Follow the standard input: instead of being evaluated once the whole standard input is read,
+// the expressions are evaluated again whenever new input arrives, at most once per given interval.
+// The standard input is expected to be captured by a rolling capture.
+func (ui *UI) Follow(interval time.Duration) {
+	ui.followInterval = interval
+}
+
+// Helper function to show the progress of the standard input until it is fully read,
+// and to evaluate the expressions again once it is, or on new input in follow mode
+func (ui *UI) watchStdin() {
+	ticker := time.NewTicker(stdinProgressInterval)
+	defer ticker.Stop()
+	var evaluated, shown program.CaptureState
+	var evaluatedAt time.Time
+	for {
+		select {
+		case <-ui.stdin.Done():
+			ui.App.QueueUpdateDraw(func() {
+				ui.updateStatusText()
+				if !ui.followPaused {
+					ui.scheduleEvaluation()
+				}
+			})
+			return
+		case <-ticker.C:
+			state := ui.stdin.State()
+			changed := state.Size != evaluated.Size || state.Discarded != evaluated.Discarded
+			follow := ui.followInterval > 0 && changed && time.Since(evaluatedAt) >= ui.followInterval
+			if follow {
+				evaluated, evaluatedAt = state, time.Now()
+			}
+			// the status is only drawn again when it changed, pausing and resuming drawing it themselves
+			if !follow && state.Size == shown.Size && state.Discarded == shown.Discarded {
+				continue
+			}
+			shown = state
+			ui.App.QueueUpdateDraw(func() {
+				ui.updateStatusText()
+				if follow && !ui.followPaused {
+					ui.scheduleEvaluation()
+				}
+			})
+		}
+	}
+}
+
+// Helper function to pause or resume following the standard input.
+// While paused, the expressions are evaluated against a snapshot of the standard input taken when pausing.
+func (ui *UI) toggleFollow() {
+	if ui.followPaused {
+		ui.followPaused = false
+		_ = os.Remove(ui.stdinTmpFile)
+		ui.stdinTmpFile = ui.stdin.File
+	} else {
+		snapshot, err := ui.stdin.Snapshot()
+		if err != nil {
+			ui.StatusText.SetText(fmt.Sprintf(" %scould not pause: %s[-]", colorTag(ui.Theme.ErrorColor), tview.Escape(err.Error())))
+			return
+		}
+		ui.followPaused = true
+		ui.stdinTmpFile = snapshot
+	}
+	ui.updateFileOptionsText()
+	ui.updateStatusText()
+	ui.scheduleEvaluation()
+}
+
+// Helper function to remove the files holding the standard input, on exit
+func (ui *UI) removeStdin() {
+	_ = os.Remove(ui.stdinTmpFile)
+	if ui.stdin != nil {
+		_ = os.Remove(ui.stdin.File)
+	}
+}
+
+// Returns the state of the standard input to show in the status line: its progress while it is read,
+// then whether it was truncated or could not be fully read
+func (ui *UI) stdinStatus() string {
+	if ui.stdin == nil {
+		return ""
+	}
+	state := ui.stdin.State()
+	switch {
+	case ui.followInterval > 0 && !state.Done:
+		return ui.followStatus(state)
+	case !state.Done:
+		return fmt.Sprintf(" reading the standard input: %s so far", formatInputSize(state.Size))
+	case state.Err != nil:
+		return fmt.Sprintf(" %serror reading the standard input: %s[-]", colorTag(ui.Theme.ErrorColor), tview.Escape(state.Err.Error()))
+	case state.Truncated && ui.stdin.Rolling:
+		return fmt.Sprintf(" %sstandard input truncated to its last %s (see --max-stdin)[-]", colorTag(ui.Theme.ErrorColor), formatInputSize(state.Size))
+	case state.Truncated:
+		return fmt.Sprintf(" %sstandard input truncated to %s (see --max-stdin)[-]", colorTag(ui.Theme.ErrorColor), formatInputSize(ui.stdin.MaxSize))
+	}
+	return ""
+}
+
+// Returns the state of the standard input followed, to show in the status line
+func (ui *UI) followStatus(state program.CaptureState) string {
+	if ui.followPaused {
+		return fmt.Sprintf(" following the standard input: %spaused[-] (F7 to resume)", colorTag(ui.Theme.KeywordColor))
+	}
+	status := fmt.Sprintf(" following the standard input: %s kept", formatInputSize(state.Size))
+	if state.Discarded > 0 {
+		status += fmt.Sprintf(", %s discarded", formatInputSize(state.Discarded))
+	}
+	return status + " (F7 to pause)"
+}
+
+// Helper function to format the size of an input, in bytes below one MiB
+func formatInputSize(size int64) string {
+	if size < 1<<20 {
+		return fmt.Sprintf("%d bytes", size)
+	}
+	return formatSize(size)
+}
@@ -0,0 +1,201 @@
+package ui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+)
+
+// Returns the InputField used for the expressions of argument templates following the first one
+func expressionInput() *tview.InputField {
+	return tview.NewInputField().
+		SetPlaceholder("<positional argument>").
+		SetPlaceholderStyle(tcell.StyleDefault)
+}
+
+// Helper function returning a TextView showing the given text in the command bar
+func (ui *UI) commandBarText(text string) *tview.TextView {
+	t := tview.NewTextView().
+		SetText(text)
+	t.SetBackgroundColor(ui.Theme.BackGroundColor)
+	t.SetTextColor(ui.Theme.KeywordColor)
+	return t
+}
+
+// Helper function to lay out the command bar for the selected stage.
+// Without an argument template, the command bar is made of the options, the expression
+// and the input files; otherwise it follows the template of the program.
+func (ui *UI) layoutChildFlex() {
+	s := ui.stages[ui.selectedStage]
+	// only the first stage reads the input files
+	showFiles := !ui.hideFileElements && ui.selectedStage == 0
+
+	ui.ChildFlex.Clear()
+	ui.expressionQuotes = nil
+	ui.ChildFlex.AddItem(ui.CommandText, len(ui.Label)+4, 1, false)
+	if len(s.program.Template) > 0 {
+		showFiles = ui.layoutTemplate(s.program, showFiles)
+	} else {
+		ui.setExpressionInputs(0)
+		ui.ChildFlex.AddItem(ui.OptionsInput, 17, 1, false).
+			AddItem(ui.endOptionsSeparator()).
+			AddItem(ui.OpeningQuoteText, 1, 1, false).
+			AddItem(ui.ArgumentsInput, 22, 1, false).
+			AddItem(ui.ClosingQuoteText, 1, 1, false)
+		ui.fields = []tview.Primitive{ui.OptionsInput, ui.ArgumentsInput}
+		if showFiles {
+			ui.ChildFlex.AddItem(ui.endArgumentsSeparator()).
+				AddItem(ui.FileOptionsText, 0, 1, false)
+		}
+	}
+
+	if showFiles {
+		ui.ChildFlex.AddItem(tview.NewBox().SetBackgroundColor(ui.Theme.BackGroundColor), 2, 1, false)
+	} else {
+		ui.ChildFlex.AddItem(tview.NewBox().SetBackgroundColor(ui.Theme.BackGroundColor), 0, 1, false)
+	}
+	if !ui.hideFileElements {
+		ui.fields = append(ui.fields, ui.FileOptionsTreeView)
+	}
+	ui.setQuote(s.quote)
+	ui.updateCommandText()
+	ui.resizeChildFlexIfNeeded()
+}
+
+// Helper function to lay out the fields of the command bar following the template of the given program.
+// Returns whether the input files are shown.
+func (ui *UI) layoutTemplate(p program.Program, showFiles bool) bool {
+	// templates are validated when programs are defined
+	parts, _ := program.ParseTemplate(p.Template)
+	ui.setExpressionInputs(program.TemplateExpressions(parts) - 1)
+	ui.fields = nil
+	filesShown := false
+
+	for i, part := range parts {
+		if i > 0 {
+			ui.ChildFlex.AddItem(ui.commandBarText(" "), 1, 1, false)
+		}
+		switch part.Kind {
+		case program.TemplateOptions:
+			ui.ChildFlex.AddItem(ui.OptionsInput, 17, 1, false)
+			ui.fields = append(ui.fields, ui.OptionsInput)
+		case program.TemplateExpression:
+			opening, input, closing := ui.OpeningQuoteText, ui.ArgumentsInput, ui.ClosingQuoteText
+			if part.Index > 0 {
+				opening, closing = ui.commandBarText(""), ui.commandBarText("")
+				ui.expressionQuotes = append(ui.expressionQuotes, opening, closing)
+				input = ui.ExpressionInputs[part.Index-1]
+			}
+			ui.ChildFlex.AddItem(opening, 1, 1, false).
+				AddItem(input, 22, 1, false).
+				AddItem(closing, 1, 1, false)
+			if ui.fieldIndex(input) < 0 {
+				ui.fields = append(ui.fields, input)
+			}
+		case program.TemplateFiles:
+			if showFiles && p.FilePlacement != program.FilesOnStdin {
+				ui.ChildFlex.AddItem(ui.FileOptionsText, 0, 1, false)
+				filesShown = true
+			}
+		default:
+			ui.ChildFlex.AddItem(ui.commandBarText(part.Word), len(part.Word), 1, false)
+		}
+	}
+
+	if showFiles && p.FilePlacement == program.FilesOnStdin {
+		ui.ChildFlex.AddItem(ui.commandBarText(" < "), 3, 1, false).
+			AddItem(ui.FileOptionsText, 0, 1, false)
+		filesShown = true
+	}
+	return filesShown
+}
+
+// Helper function to provide the given number of inputs for the expressions following the first one
+func (ui *UI) setExpressionInputs(n int) {
+	for len(ui.ExpressionInputs) < n {
+		input := expressionInput()
+		ui.configExpressionInput(input)
+		ui.ExpressionInputs = append(ui.ExpressionInputs, input)
+	}
+	if n < 0 {
+		n = 0
+	}
+	ui.ExpressionInputs = ui.ExpressionInputs[:n]
+}
+
+// Helper function returning the index of the given field of the command bar, or -1
+func (ui *UI) fieldIndex(field tview.Primitive) int {
+	for i, f := range ui.fields {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// Helper function to move the focus to the next (or previous, with a negative step) field of the command bar
+func (ui *UI) cycleFocus(from tview.Primitive, step int) {
+	if len(ui.fields) == 0 {
+		return
+	}
+	i := ui.fieldIndex(from)
+	if i < 0 {
+		ui.App.SetFocus(ui.fields[0])
+		return
+	}
+	i = (i + step + len(ui.fields)) % len(ui.fields)
+	ui.App.SetFocus(ui.fields[i])
+}
+
+// Helper function to move the focus to the first field of the command bar
+func (ui *UI) focusCommandBar() {
+	if len(ui.fields) == 0 {
+		ui.App.SetFocus(ui.OutputView)
+		return
+	}
+	ui.App.SetFocus(ui.fields[0])
+}
+
+// Helper function to show the given quote around every expression
+func (ui *UI) setQuote(quote string) {
+	ui.OpeningQuoteText.SetText(quote)
+	ui.ClosingQuoteText.SetText(quote)
+	for _, t := range ui.expressionQuotes {
+		t.SetText(quote)
+	}
+}
+
+// Helper function to switch between single and double quotes
+func (ui *UI) toggleQuote() {
+	if ui.OpeningQuoteText.GetText(false) == "'" {
+		ui.setQuote("\"")
+	} else {
+		ui.setQuote("'")
+	}
+}
+
+// Function for configuring an InputField of the expressions following the first one
+func (ui *UI) configExpressionInput(input *tview.InputField) {
+	input.SetChangedFunc(ui.changedInputField())
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyRune, tcell.KeyDelete, tcell.KeyBackspace2:
+			ui.OutputView.ScrollToBeginning()
+		case tcell.KeyTab:
+			ui.cycleFocus(input, 1)
+		case tcell.KeyBacktab:
+			ui.cycleFocus(input, -1)
+		case tcell.KeyEnter:
+			ui.ActiveInput = &ui.ArgumentsInput
+			ui.App.SetFocus(ui.OutputView)
+		case tcell.KeyCtrlSpace:
+			ui.toggleQuote()
+		}
+		return event
+	})
+
+	input.SetFieldTextColor(ui.Theme.TextColor)
+	input.SetFieldBackgroundColor(ui.Theme.BackGroundColor)
+	input.SetBackgroundColor(ui.Theme.BackGroundColor)
+	input.SetPlaceholderTextColor(ui.Theme.TextColor)
+}
@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"fmt"
+
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -10,6 +12,7 @@ type Theme struct {
 	KeywordColor    tcell.Color
 	TextColor       tcell.Color
 	BorderColor     tcell.Color
+	ErrorColor      tcell.Color
 }
 
 var Themes = map[string]Theme{
@@ -18,6 +21,7 @@ var Themes = map[string]Theme{
 		TitleColor:      tcell.GetColor("#aa0000"),
 		KeywordColor:    tcell.GetColor("#ff6ac1"),
 		TextColor:       tcell.GetColor("#5af78e"),
+		ErrorColor:      tcell.GetColor("#ff5c57"),
 	},
 	"dracula": {
 		BackGroundColor: tcell.GetColor("#282a36"),
@@ -25,12 +29,14 @@ var Themes = map[string]Theme{
 		KeywordColor:    tcell.GetColor("#ff79c6"),
 		TextColor:       tcell.GetColor("#f1fa8c"),
 		BorderColor:     tcell.GetColor("#f8f8f2"),
+		ErrorColor:      tcell.GetColor("#ff5555"),
 	},
 	"fruity": {
 		BackGroundColor: tcell.GetColor("#111111"),
 		TitleColor:      tcell.GetColor("#ff0086"),
 		KeywordColor:    tcell.GetColor("#fb660a"),
 		TextColor:       tcell.GetColor("#0086d2"),
+		ErrorColor:      tcell.GetColor("#ff0007"),
 	},
 	"monokai": {
 		BackGroundColor: tcell.GetColor("#272822"),
@@ -38,12 +44,14 @@ var Themes = map[string]Theme{
 		KeywordColor:    tcell.GetColor("#f92672"),
 		TextColor:       tcell.GetColor("#e6db74"),
 		BorderColor:     tcell.GetColor("#f8f8f2"),
+		ErrorColor:      tcell.GetColor("#fd971f"),
 	},
 	"vim": {
 		BackGroundColor: tcell.GetColor("#000000"),
 		TitleColor:      tcell.GetColor("#56d364"),
 		KeywordColor:    tcell.GetColor("#cd00cd"),
 		TextColor:       tcell.GetColor("#cd0000"),
+		ErrorColor:      tcell.GetColor("#ff5f5f"),
 	},
 	"witchhazel": {
 		BackGroundColor: tcell.GetColor("#433e56"),
@@ -51,5 +59,11 @@ var Themes = map[string]Theme{
 		KeywordColor:    tcell.GetColor("#ffb8d1"),
 		TextColor:       tcell.GetColor("#1bc5e0"),
 		BorderColor:     tcell.GetColor("#f8f8f2"),
+		ErrorColor:      tcell.GetColor("#e0546c"),
 	},
 }
+
+// Returns the tag used to switch to the given color in dynamic color text
+func colorTag(color tcell.Color) string {
+	return fmt.Sprintf("[#%06x]", color.Hex())
+}
@@ -2,12 +2,14 @@ package ui
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
@@ -28,23 +30,61 @@ type UI struct {
 	ArgumentsInput         *tview.InputField
 	ArgumentsInputWide     *tview.TextArea
 	ArgumentsInputWideFlex *tview.Flex
+	StatusText             *tview.TextView
 	ClosingQuoteText       *tview.TextView
+	ExpressionInputs       []*tview.InputField
 	EndArgumentsText       *tview.TextView
 	hideFileElements       bool
 	stdinTmpFile           string
+	stdin                  *program.Capture
+	followInterval         time.Duration
+	followPaused           bool
 	FileOptionsText        *tview.TextView
 	FileOptionsTreeNode    *tview.TreeNode
 	FileOptionsTreeView    *tview.TreeView
 	FileOptionsInputMap    map[string]bool
 	FileOptionsInputSlice  []string
 	OutputView             *tview.TextView
+	ChangesView            *tview.TextView
+	CompareView            *tview.TextView
+	VariablesView          *tview.TextView
+	MatchesView            *tview.TextView
+	BenchmarkView          *tview.TextView
+	HelpView               *tview.TextView
 	FileView               *tview.TextView
+	EnvironmentInput       *tview.TextArea
+	EnvironmentFlex        *tview.Flex
+	PipelineFlex           *tview.Flex
+	PipelineText           *tview.TextView
+	StageInput             *tview.InputField
 	ChildFlex              *tview.Flex
 	Flex                   *tview.Flex
 	ActiveInput            **tview.InputField
 	ActiveFlex             **tview.Flex
 	ThemeName              string
 	Theme                  Theme
+	scheduler              *scheduler
+	settings               program.Settings
+	changes                []program.Change
+	environment            []string
+	stages                 []*stage
+	selectedStage          int
+	loadingStage           bool
+	results                []program.Result
+	compare                *program.Program
+	comparison             []stageComparison
+	baseline               []program.Command
+	benchmarks             []program.Benchmark
+	benchmarkCancel        context.CancelFunc
+	benchmarkFocus         tview.Primitive
+	helpCancel             context.CancelFunc
+	helpFocus              tview.Primitive
+	expressionQuotes       []*tview.TextView
+	fields                 []tview.Primitive
+	completing             bool
+	diagnostic             *program.Diagnostic
+	diagnosticStage        int
+	preselectedFiles       []string
 }
 
 type nodeReference struct {
@@ -146,6 +186,24 @@ func outputView() *tview.TextView {
 	return t
 }
 
+// Returns the TextView used for changes made in scratch mode
+func changesView() *tview.TextView {
+	t := tview.NewTextView().
+		SetDynamicColors(true)
+	t.SetBorder(true)
+	t.SetTitle(" Changes ")
+	return t
+}
+
+// Returns the TextView used for the comparison of implementations
+func compareView() *tview.TextView {
+	t := tview.NewTextView().
+		SetDynamicColors(true)
+	t.SetBorder(true)
+	t.SetTitle(" Comparison ")
+	return t
+}
+
 // Returns the TextView used for file view
 func fileView() *tview.TextView {
 	t := tview.NewTextView().
@@ -191,24 +249,37 @@ func getFileOptionsText(a *[]string) string {
 	return strings.TrimSpace(sb.String())
 }
 
+// Helper function to show the input files, which are only read by the first stage
+func (ui *UI) updateFileOptionsText() {
+	switch {
+	case ui.selectedStage > 0:
+		ui.FileOptionsText.SetText("")
+	case ui.hideFileElements:
+		ui.FileOptionsText.SetText(ui.stdinTmpFile)
+	default:
+		ui.FileOptionsText.SetText(getFileOptionsText(&ui.FileOptionsInputSlice))
+	}
+}
+
 // UI constructor
-func NewUI(program string, respectsEndOfOptions bool, stdinTmpFile string, theme string) *UI {
+func NewUI(program program.Program, compare *program.Program, stdin *program.Capture, theme string, settings program.Settings) *UI {
 	ui := &UI{
 		App:                    tview.NewApplication(),
 		ThemeName:              theme,
 		Theme:                  Themes[theme],
-		Label:                  program,
-		EndOfOptionsSeparator:  respectsEndOfOptions,
-		CommandText:            commandText(program),
+		Label:                  program.DisplayName(),
+		EndOfOptionsSeparator:  program.RespectsEndOfOptions,
+		CommandText:            commandText(program.DisplayName()),
 		OptionsInput:           optionsInput(),
 		EndOptionsText:         endOptionsText(),
 		OpeningQuoteText:       openingQuoteText(),
 		ArgumentsInput:         argumentsInput(),
 		ArgumentsInputWide:     argumentsInputWide(),
 		ArgumentsInputWideFlex: argumentsInputWideFlex(),
+		StatusText:             statusText(),
 		ClosingQuoteText:       closingQuoteText(),
 		EndArgumentsText:       endArgumentsText(),
-		stdinTmpFile:           stdinTmpFile,
+		stdin:                  stdin,
 		hideFileElements:       false,
 		FileOptionsText:        fileOptionsText(),
 		FileOptionsTreeNode:    fileOptionsTreeNode(),
@@ -216,11 +287,29 @@ func NewUI(program string, respectsEndOfOptions bool, stdinTmpFile string, theme
 		FileOptionsInputMap:    make(map[string]bool),
 		FileOptionsInputSlice:  []string{},
 		OutputView:             outputView(),
+		ChangesView:            changesView(),
+		CompareView:            compareView(),
+		VariablesView:          variablesView(),
+		MatchesView:            matchesView(),
+		BenchmarkView:          benchmarkView(),
+		HelpView:               helpView(),
 		FileView:               fileView(),
+		EnvironmentInput:       environmentInput(),
+		EnvironmentFlex:        environmentFlex(),
+		PipelineFlex:           pipelineFlex(),
+		PipelineText:           pipelineText(),
+		StageInput:             stageInput(),
 		ChildFlex:              childFlex(),
 		Flex:                   flex(),
 		ActiveInput:            nil,
 		ActiveFlex:             nil,
+		scheduler:              newScheduler(debounceDelay),
+		settings:               settings,
+		stages:                 []*stage{newStage(program)},
+		compare:                compare,
+	}
+	if stdin != nil {
+		ui.stdinTmpFile = stdin.File
 	}
 	return ui
 }
@@ -234,35 +323,216 @@ func (ui *UI) getActiveInputText() string {
 	}
 }
 
-// Helper function for evaluating expressions
-func (ui *UI) evaluateExpression() func() {
-	return func() {
-		var sb strings.Builder
-		sb.WriteString(ui.Label)
-		sb.WriteString(" ")
-		sb.WriteString(ui.OptionsInput.GetText())
-		if ui.EndOfOptionsSeparator {
-			sb.WriteString(" -- ")
-		} else {
-			sb.WriteString(" ")
+// Helper function for evaluating expressions.
+// The pipeline is built on the UI goroutine, while the programs themselves run
+// in the background so that slow evaluations never block the UI.
+func (ui *UI) scheduleEvaluation() {
+	if ui.loadingStage {
+		return
+	}
+	commands := ui.buildPipeline()
+	if ui.compare != nil {
+		ui.scheduleComparison(commands)
+		return
+	}
+	ui.scheduler.schedule(func(ctx context.Context, seq uint64) {
+		// invalid expressions are not evaluated, so that the output of the last valid one stays visible
+		stage, d := program.ValidatePipeline(ctx, commands, ui.settings)
+		if d != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			ui.App.QueueUpdateDraw(func() {
+				if ui.scheduler.isLatest(seq) {
+					ui.showDiagnostic(stage, d)
+				}
+			})
+			return
 		}
-		sb.WriteString(ui.OpeningQuoteText.GetText(false))
-		sb.WriteString(ui.getActiveInputText())
-		sb.WriteString(ui.ClosingQuoteText.GetText(false))
-		sb.WriteString(" ")
-		if !ui.EndOfOptionsSeparator {
-			sb.WriteString(" -- ")
+		results, err := program.RunPipeline(ctx, commands, ui.settings)
+		if ctx.Err() != nil {
+			return
 		}
-		if t := ui.FileOptionsText.GetText(false); t != "<input files>" {
-			if len(ui.stdinTmpFile) > 0 && len(ui.FileOptionsInputSlice) == 0 {
-				sb.WriteString(ui.stdinTmpFile)
-			} else {
-				sb.WriteString(t)
+		ui.App.QueueUpdateDraw(func() {
+			// discard results of evaluations superseded in the meantime
+			if ui.scheduler.isLatest(seq) {
+				ui.showDiagnostic(-1, nil)
+				ui.showResults(results, err)
 			}
+		})
+	})
+}
+
+// Helper function to format the duration of an evaluation
+func formatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// Helper function for displaying the results of the pipeline.
+// The output of the selected stage is shown, along with the changes made by the first one.
+func (ui *UI) showResults(results []program.Result, err error) {
+	if err != nil {
+		ui.results = nil
+		ui.OutputView.SetTitleColor(ui.Theme.ErrorColor)
+		ui.OutputView.SetTitle(" Output ")
+		ui.OutputView.SetText(colorTag(ui.Theme.ErrorColor) + tview.Escape(err.Error()))
+		return
+	}
+
+	ui.results = results
+	if ui.selectedStage < len(results) {
+		ui.showResult(results[ui.selectedStage])
+	}
+	ui.showChanges(results[0].Changes)
+}
+
+// Helper function for displaying the result of an evaluation.
+// Standard error is shown after standard output using the error color, while
+// the exit status and elapsed time are shown in the title.
+func (ui *UI) showResult(res program.Result) {
+	var sb strings.Builder
+	var highlights []program.Highlight
+	if h, ok := ui.stages[ui.selectedStage].program.Backend().(program.Highlighter); ok {
+		highlights = h.HighlightOutput(res)
+	}
+	if len(highlights) > 0 {
+		sb.WriteString(ui.highlight(res.Stdout, highlights, true))
+	} else {
+		sb.WriteString(tview.TranslateANSI(res.Stdout))
+	}
+	if len(res.Stderr) > 0 {
+		if len(res.Stdout) > 0 && !strings.HasSuffix(res.Stdout, "\n") {
+			sb.WriteString("\n")
 		}
-		out, _ := program.Run(sb.String())
-		ui.OutputView.SetText(tview.TranslateANSI(out))
+		sb.WriteString(colorTag(ui.Theme.ErrorColor))
+		sb.WriteString(tview.Escape(res.Stderr))
+		sb.WriteString("[-]")
+	}
+	if res.Diagnostic != nil {
+		sb.WriteString("\n")
+		sb.WriteString(ui.formatDiagnostic(*res.Diagnostic))
+	}
+	if res.Truncated {
+		sb.WriteString("\n")
+		sb.WriteString(colorTag(ui.Theme.KeywordColor))
+		sb.WriteString(tview.Escape(fmt.Sprintf("[output truncated at %d bytes]", ui.settings.MaxOutput)))
+	}
+	if res.TimedOut {
+		sb.WriteString("\n")
+		sb.WriteString(colorTag(ui.Theme.KeywordColor))
+		sb.WriteString(tview.Escape(fmt.Sprintf("[timed out after %s]", ui.settings.Timeout)))
+	}
+
+	if res.Success() {
+		ui.OutputView.SetTitleColor(ui.Theme.KeywordColor)
+	} else {
+		ui.OutputView.SetTitleColor(ui.Theme.ErrorColor)
+	}
+	status := fmt.Sprintf("%s, %s", res.Status(), formatDuration(res.Duration))
+	if res.Cached {
+		status += ", cached"
+	}
+	if len(ui.stages) > 1 {
+		status = fmt.Sprintf("stage %d/%d: %s", ui.selectedStage+1, len(ui.stages), status)
 	}
+	ui.OutputView.SetTitle(" Output (" + status + ") ")
+	ui.OutputView.SetText(sb.String())
+	ui.showVariables(res)
+	ui.showMatches(res)
+}
+
+// Helper function to show the line of the expression holding the error of the given diagnostic,
+// with the character at fault highlighted and pointed at
+func (ui *UI) formatDiagnostic(d program.Diagnostic) string {
+	start := strings.LastIndex(d.Expression[:d.Offset], "\n") + 1
+	end := len(d.Expression)
+	if i := strings.Index(d.Expression[d.Offset:], "\n"); i >= 0 {
+		end = d.Offset + i
+	}
+	before, at, after := d.Expression[start:d.Offset], "", d.Expression[d.Offset:end]
+	if len(after) > 0 {
+		_, size := utf8.DecodeRuneInString(after)
+		at, after = after[:size], after[size:]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[-]")
+	sb.WriteString(tview.Escape(before))
+	sb.WriteString(colorTag(ui.Theme.ErrorColor) + "[::r]")
+	sb.WriteString(tview.Escape(at))
+	sb.WriteString("[::-][-]")
+	sb.WriteString(tview.Escape(after))
+	sb.WriteString("\n")
+	sb.WriteString(strings.Repeat(" ", tview.TaggedStringWidth(tview.Escape(before))))
+	sb.WriteString(colorTag(ui.Theme.ErrorColor))
+	sb.WriteString(tview.Escape(fmt.Sprintf("^ %s", d.Message)))
+	sb.WriteString("[-]")
+	return sb.String()
+}
+
+// Helper function to color a unified diff
+func (ui *UI) colorizeDiff(text string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			sb.WriteString(colorTag(ui.Theme.KeywordColor))
+		case strings.HasPrefix(line, "@@"):
+			sb.WriteString(colorTag(ui.Theme.TitleColor))
+		case strings.HasPrefix(line, "+"):
+			sb.WriteString(colorTag(ui.Theme.TextColor))
+		case strings.HasPrefix(line, "-"):
+			sb.WriteString(colorTag(ui.Theme.ErrorColor))
+		default:
+			sb.WriteString("[-]")
+		}
+		sb.WriteString(tview.Escape(line))
+	}
+	return sb.String()
+}
+
+// Helper function for displaying the changes made to the scratch copies
+func (ui *UI) showChanges(changes []program.Change) {
+	if !ui.settings.Scratch {
+		return
+	}
+	ui.changes = changes
+	if len(changes) == 0 {
+		ui.ChangesView.SetTitle(" Changes ")
+		ui.ChangesView.SetText("")
+		return
+	}
+
+	var sb strings.Builder
+	for _, change := range changes {
+		sb.WriteString(ui.colorizeDiff(change.Diff()))
+	}
+	files := "files"
+	if len(changes) == 1 {
+		files = "file"
+	}
+	ui.ChangesView.SetTitle(fmt.Sprintf(" Changes (%d %s, Ctrl+P to apply) ", len(changes), files))
+	ui.ChangesView.SetText(sb.String())
+	ui.ChangesView.ScrollToBeginning()
+}
+
+// Helper function to write the changes made to the scratch copies to the real files
+func (ui *UI) applyChanges() {
+	if len(ui.changes) == 0 {
+		return
+	}
+	if err := program.ApplyChanges(ui.changes); err != nil {
+		ui.ChangesView.SetTitle(" Changes (not applied) ")
+		ui.ChangesView.SetText(colorTag(ui.Theme.ErrorColor) + tview.Escape(err.Error()))
+		return
+	}
+	ui.changes = nil
+	ui.ChangesView.SetTitle(" Changes (applied) ")
+	ui.ChangesView.SetText("")
+	ui.scheduleEvaluation()
 }
 
 // Helper function to resize flex based on argument input size
@@ -280,14 +550,14 @@ func (ui *UI) resizeChildFlexIfNeeded() {
 // Callback function for InputField
 func (ui *UI) changedInputField() func(string) {
 	return func(text string) {
-		go ui.App.QueueUpdateDraw(ui.evaluateExpression())
+		ui.scheduleEvaluation()
 	}
 }
 
 // Callback function for TextView
 func (ui *UI) changedText() func() {
 	return func() {
-		go ui.App.QueueUpdateDraw(ui.evaluateExpression())
+		ui.scheduleEvaluation()
 	}
 }
 
@@ -302,6 +572,12 @@ func isExtensionInvalid(fileExtension string) bool {
 	return false
 }
 
+// Helper function to tell whether the program accepts files with the given extension
+func (ui *UI) isExtensionAccepted(fileExtension string) bool {
+	extensions := ui.stages[0].program.Extensions
+	return len(extensions) == 0 || slices.Contains(extensions, fileExtension)
+}
+
 // Helper function for populating nodes of TreeNode
 func add(target *tview.TreeNode, path string, ui *UI) {
 	files, err := ioutil.ReadDir(path)
@@ -326,7 +602,7 @@ func add(target *tview.TreeNode, path string, ui *UI) {
 			scanner.Scan()
 			text := string(scanner.Text())
 			fileExtension := filepath.Ext(file.Name())
-			if utf8.ValidString(text) && !isExtensionInvalid(fileExtension) {
+			if utf8.ValidString(text) && !isExtensionInvalid(fileExtension) && ui.isExtensionAccepted(fileExtension) {
 				target.AddChild(node)
 				nodeRef := nodeReference{filepath.Join(path, file.Name()), text}
 				node.SetReference(nodeRef)
@@ -351,16 +627,22 @@ func (ui *UI) configQuotes() {
 
 // Function for configuring OptionsInput InputField
 func (ui *UI) configOptionsInput() {
+	ui.OptionsInput.SetText(ui.stages[0].options)
 	ui.OptionsInput.SetChangedFunc(ui.changedInputField())
+	ui.OptionsInput.SetAutocompleteFunc(ui.completeOption)
+	ui.OptionsInput.SetAutocompletedFunc(ui.completedOption)
 
 	if ui.hideFileElements {
 		ui.OptionsInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 			key := event.Key()
+			if ui.completingOption(key) {
+				return event
+			}
 			switch key {
 			case tcell.KeyTab:
-				ui.App.SetFocus(ui.ArgumentsInput)
+				ui.cycleFocus(ui.OptionsInput, 1)
 			case tcell.KeyBacktab:
-				ui.App.SetFocus(ui.ArgumentsInput)
+				ui.cycleFocus(ui.OptionsInput, -1)
 			case tcell.KeyEnter:
 				ui.ActiveInput = &ui.OptionsInput
 				ui.App.SetFocus(ui.OutputView)
@@ -372,11 +654,14 @@ func (ui *UI) configOptionsInput() {
 	} else {
 		ui.OptionsInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 			key := event.Key()
+			if ui.completingOption(key) {
+				return event
+			}
 			switch key {
 			case tcell.KeyTab:
-				ui.App.SetFocus(ui.ArgumentsInput)
+				ui.cycleFocus(ui.OptionsInput, 1)
 			case tcell.KeyBacktab:
-				ui.App.SetFocus(ui.FileOptionsTreeView)
+				ui.cycleFocus(ui.OptionsInput, -1)
 			case tcell.KeyEnter:
 				ui.ActiveInput = &ui.OptionsInput
 				ui.App.SetFocus(ui.OutputView)
@@ -391,6 +676,9 @@ func (ui *UI) configOptionsInput() {
 	ui.OptionsInput.SetFieldBackgroundColor(ui.Theme.BackGroundColor)
 	ui.OptionsInput.SetBackgroundColor(ui.Theme.BackGroundColor)
 	ui.OptionsInput.SetPlaceholderTextColor(ui.Theme.TextColor)
+	ui.OptionsInput.SetAutocompleteStyles(ui.Theme.BackGroundColor,
+		tcell.StyleDefault.Foreground(ui.Theme.TextColor).Background(ui.Theme.BackGroundColor),
+		tcell.StyleDefault.Foreground(ui.Theme.BackGroundColor).Background(ui.Theme.KeywordColor))
 }
 
 // Function for configuring ArgumentsInput InputField
@@ -411,20 +699,14 @@ func (ui *UI) configArgumentsInput() {
 				ui.OutputView.ScrollToBeginning()
 				ui.resizeChildFlexIfNeeded()
 			case tcell.KeyTab:
-				ui.App.SetFocus(ui.OptionsInput)
+				ui.cycleFocus(ui.ArgumentsInput, 1)
 			case tcell.KeyBacktab:
-				ui.App.SetFocus(ui.OptionsInput)
+				ui.cycleFocus(ui.ArgumentsInput, -1)
 			case tcell.KeyEnter:
 				ui.ActiveInput = &ui.ArgumentsInput
 				ui.App.SetFocus(ui.OutputView)
 			case tcell.KeyCtrlSpace:
-				if ui.OpeningQuoteText.GetText(false) == "'" {
-					ui.OpeningQuoteText.SetText("\"")
-					ui.ClosingQuoteText.SetText("\"")
-				} else {
-					ui.OpeningQuoteText.SetText("'")
-					ui.ClosingQuoteText.SetText("'")
-				}
+				ui.toggleQuote()
 			case tcell.KeyCtrlO:
 				ui.ArgumentsInputWide.SetText(ui.ArgumentsInput.GetText(), true)
 				ui.ActiveFlex = &ui.ArgumentsInputWideFlex
@@ -447,20 +729,14 @@ func (ui *UI) configArgumentsInput() {
 				ui.OutputView.ScrollToBeginning()
 				ui.resizeChildFlexIfNeeded()
 			case tcell.KeyTab:
-				ui.App.SetFocus(ui.FileOptionsTreeView)
+				ui.cycleFocus(ui.ArgumentsInput, 1)
 			case tcell.KeyBacktab:
-				ui.App.SetFocus(ui.OptionsInput)
+				ui.cycleFocus(ui.ArgumentsInput, -1)
 			case tcell.KeyEnter:
 				ui.ActiveInput = &ui.ArgumentsInput
 				ui.App.SetFocus(ui.OutputView)
 			case tcell.KeyCtrlSpace:
-				if ui.OpeningQuoteText.GetText(false) == "'" {
-					ui.OpeningQuoteText.SetText("\"")
-					ui.ClosingQuoteText.SetText("\"")
-				} else {
-					ui.OpeningQuoteText.SetText("'")
-					ui.ClosingQuoteText.SetText("'")
-				}
+				ui.toggleQuote()
 			case tcell.KeyCtrlO:
 				ui.ArgumentsInputWide.SetText(ui.ArgumentsInput.GetText(), true)
 				ui.ActiveFlex = &ui.ArgumentsInputWideFlex
@@ -560,6 +836,46 @@ func (ui *UI) configFileOptionsTreeNode() {
 	add(ui.FileOptionsTreeNode, rootDir, ui)
 }
 
+// Helper function to select the given input files, marking those shown in the file picker.
+// The directories holding the files are expanded.
+func (ui *UI) selectFiles(files []string) {
+	for _, file := range files {
+		if ui.FileOptionsInputMap[file] {
+			continue
+		}
+		updateFileOptionsInput(ui.FileOptionsInputMap, &ui.FileOptionsInputSlice, file)
+		if node := ui.fileNode(file); node != nil {
+			node.SetColor(ui.Theme.KeywordColor)
+		}
+	}
+	ui.updateFileOptionsText()
+}
+
+// Helper function returning the node of the file picker for the given path, relative to the working directory, if any
+func (ui *UI) fileNode(path string) *tview.TreeNode {
+	node := ui.FileOptionsTreeNode
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := range parts {
+		nodePath := filepath.Join(parts[:i+1]...)
+		if i > 0 && len(node.GetChildren()) == 0 {
+			add(node, getNodePath(node), ui)
+		}
+		node.SetExpanded(true)
+		var child *tview.TreeNode
+		for _, c := range node.GetChildren() {
+			if getNodePath(c) == nodePath {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
+
 // Function for configuring FileOptionsTreeView TreeView
 func (ui *UI) configFileOptionsTreeView() {
 	ui.FileOptionsTreeView.SetRoot(ui.FileOptionsTreeNode).SetCurrentNode(ui.FileOptionsTreeNode)
@@ -579,7 +895,7 @@ func (ui *UI) configFileOptionsTreeView() {
 			}
 			// when a file is selected, update the sorted, unique list of files
 			updateFileOptionsInput(ui.FileOptionsInputMap, &ui.FileOptionsInputSlice, nodePath)
-			ui.FileOptionsText.SetText(getFileOptionsText(&ui.FileOptionsInputSlice))
+			ui.updateFileOptionsText()
 			ui.OutputView.ScrollToBeginning()
 			return
 		}
@@ -595,11 +911,11 @@ func (ui *UI) configFileOptionsTreeView() {
 		key := event.Key()
 		switch key {
 		case tcell.KeyEsc:
-			ui.App.SetFocus(ui.ArgumentsInput)
+			ui.cycleFocus(ui.FileOptionsTreeView, -1)
 		case tcell.KeyBacktab:
-			ui.App.SetFocus(ui.ArgumentsInput)
+			ui.cycleFocus(ui.FileOptionsTreeView, -1)
 		case tcell.KeyTab:
-			ui.App.SetFocus(ui.OptionsInput)
+			ui.cycleFocus(ui.FileOptionsTreeView, 1)
 
 		case tcell.KeyCtrlO:
 			if ui.FileOptionsTreeView.GetCurrentNode() == ui.FileOptionsTreeView.GetRoot() {
@@ -628,6 +944,17 @@ func (ui *UI) configFileOptionsTreeView() {
 // Function for configuring OutputView TextView
 func (ui *UI) configOutputView() {
 	ui.OutputView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab || event.Key() == tcell.KeyBacktab {
+			if ui.settings.Scratch {
+				ui.App.SetFocus(ui.ChangesView)
+			} else if ui.compare != nil {
+				ui.App.SetFocus(ui.CompareView)
+			} else if ui.showsVariables() {
+				ui.App.SetFocus(ui.VariablesView)
+			} else if ui.showsMatches() {
+				ui.App.SetFocus(ui.MatchesView)
+			}
+		}
 		if event.Key() == tcell.KeyEsc {
 			if ui.ActiveFlex == &ui.Flex {
 				ui.App.SetRoot(ui.Flex, true)
@@ -646,6 +973,48 @@ func (ui *UI) configOutputView() {
 	ui.OutputView.SetTextColor(ui.Theme.BorderColor)
 }
 
+// Function for configuring ChangesView TextView
+func (ui *UI) configChangesView() {
+	ui.ChangesView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		key := event.Key()
+		switch key {
+		case tcell.KeyEsc:
+			ui.App.SetFocus(ui.OutputView)
+		case tcell.KeyTab:
+			ui.App.SetFocus(ui.OutputView)
+		case tcell.KeyBacktab:
+			ui.App.SetFocus(ui.OutputView)
+		}
+		return event
+	})
+
+	ui.ChangesView.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.ChangesView.SetTitleColor(ui.Theme.KeywordColor)
+	ui.ChangesView.SetBorderColor(ui.Theme.BorderColor)
+	ui.ChangesView.SetTextColor(ui.Theme.BorderColor)
+}
+
+// Function for configuring CompareView TextView
+func (ui *UI) configCompareView() {
+	ui.CompareView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		key := event.Key()
+		switch key {
+		case tcell.KeyEsc:
+			ui.App.SetFocus(ui.OutputView)
+		case tcell.KeyTab:
+			ui.App.SetFocus(ui.OutputView)
+		case tcell.KeyBacktab:
+			ui.App.SetFocus(ui.OutputView)
+		}
+		return event
+	})
+
+	ui.CompareView.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.CompareView.SetTitleColor(ui.Theme.KeywordColor)
+	ui.CompareView.SetBorderColor(ui.Theme.BorderColor)
+	ui.CompareView.SetTextColor(ui.Theme.BorderColor)
+}
+
 // Function for configuring FileView TextView
 func (ui *UI) configFileView() {
 	ui.FileView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -684,7 +1053,9 @@ func (ui *UI) endArgumentsSeparator() (*tview.TextView, int, int, bool) {
 	ui.EndArgumentsText.SetBackgroundColor(ui.Theme.BackGroundColor)
 	ui.EndArgumentsText.SetTextColor(ui.Theme.KeywordColor)
 
-	if ui.EndOfOptionsSeparator {
+	if ui.stages[0].program.FilePlacement == program.FilesOnStdin {
+		return ui.EndArgumentsText.SetText(" < "), 3, 1, false
+	} else if ui.EndOfOptionsSeparator {
 		return ui.EndArgumentsText.SetText(" "), 1, 1, false
 	} else {
 		return ui.EndArgumentsText.SetText(" -- "), 4, 1, false
@@ -693,29 +1064,33 @@ func (ui *UI) endArgumentsSeparator() (*tview.TextView, int, int, bool) {
 
 // Function for configuring ChildFlex Flex
 func (ui *UI) configChildFlex() {
-	if ui.hideFileElements {
-		ui.ChildFlex.SetDirection(tview.FlexColumn).
-			AddItem(ui.CommandText, len(ui.Label)+4, 1, false).
-			AddItem(ui.OptionsInput, 17, 1, false).
-			AddItem(ui.endOptionsSeparator()).
-			AddItem(ui.OpeningQuoteText, 1, 1, false).
-			AddItem(ui.ArgumentsInput, 22, 1, false).
-			AddItem(ui.ClosingQuoteText, 1, 1, false).
-			AddItem(tview.NewBox().SetBackgroundColor(ui.Theme.BackGroundColor), 0, 1, false)
-	} else {
-		ui.ChildFlex.SetDirection(tview.FlexColumn).
-			AddItem(ui.CommandText, len(ui.Label)+4, 1, false).
-			AddItem(ui.OptionsInput, 17, 1, false).
-			AddItem(ui.endOptionsSeparator()).
-			AddItem(ui.OpeningQuoteText, 1, 1, false).
-			AddItem(ui.ArgumentsInput, 22, 1, false).
-			AddItem(ui.ClosingQuoteText, 1, 1, false).
-			AddItem(ui.endArgumentsSeparator()).
-			AddItem(ui.FileOptionsText, 0, 1, false).
-			AddItem(tview.NewBox().SetBackgroundColor(ui.Theme.BackGroundColor), 2, 1, false)
+	ui.ChildFlex.SetDirection(tview.FlexColumn)
+	ui.layoutChildFlex()
+	ui.ChildFlex.SetBackgroundColor(ui.Theme.BackGroundColor)
+}
 
+// Helper function returning the pane with the output, along with the changes
+// in scratch mode, the comparison of implementations in comparison mode and
+// the variables of the program or the matches of the expression when they are reported
+func (ui *UI) outputPane() tview.Primitive {
+	if !ui.settings.Scratch && ui.compare == nil && !ui.showsVariables() && !ui.showsMatches() {
+		return ui.OutputView
 	}
-	ui.ChildFlex.SetBackgroundColor(ui.Theme.BackGroundColor)
+	pane := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(ui.OutputView, 0, 2, false)
+	if ui.settings.Scratch {
+		pane.AddItem(ui.ChangesView, 0, 1, false)
+	}
+	if ui.compare != nil {
+		pane.AddItem(ui.CompareView, 0, 1, false)
+	}
+	if ui.showsVariables() {
+		pane.AddItem(ui.VariablesView, 0, 1, false)
+	}
+	if ui.showsMatches() {
+		pane.AddItem(ui.MatchesView, 0, 1, false)
+	}
+	return pane
 }
 
 // Function for configuring Flex Flex
@@ -723,16 +1098,18 @@ func (ui *UI) configFlex() {
 
 	if ui.hideFileElements {
 		ui.Flex.AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
-			AddItem(tview.NewBox().SetBackgroundColor(ui.Theme.BackGroundColor), 2, 1, false).
-			AddItem(ui.ChildFlex, 3, 1, false).
+			AddItem(ui.PipelineFlex, 2, 1, false).
+			AddItem(ui.ChildFlex, 1, 1, false).
+			AddItem(ui.StatusText, 2, 1, false).
 			AddItem(tview.NewFlex().SetDirection(tview.FlexColumn).
-				AddItem(ui.OutputView, 0, 10, false), 0, 1, false), 0, 1, false)
+				AddItem(ui.outputPane(), 0, 10, false), 0, 1, false), 0, 1, false)
 	} else {
 		ui.Flex.AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
-			AddItem(tview.NewBox().SetBackgroundColor(ui.Theme.BackGroundColor), 2, 1, false).
-			AddItem(ui.ChildFlex, 3, 1, false).
+			AddItem(ui.PipelineFlex, 2, 1, false).
+			AddItem(ui.ChildFlex, 1, 1, false).
+			AddItem(ui.StatusText, 2, 1, false).
 			AddItem(tview.NewFlex().SetDirection(tview.FlexColumn).
-				AddItem(ui.OutputView, 0, 10, false).
+				AddItem(ui.outputPane(), 0, 10, false).
 				AddItem(ui.FileOptionsTreeView, 0, 2, false), 0, 1, false), 0, 1, false)
 	}
 	ui.Flex.SetBorder(true)
@@ -742,10 +1119,19 @@ func (ui *UI) configFlex() {
 	ui.Flex.SetBorderColor(ui.Theme.BorderColor)
 }
 
+// Preload the command bar with the given expressions and select the given input files, before initializing the UI
+func (ui *UI) Preload(expressions []string, files []string) {
+	s := ui.stages[0]
+	if len(expressions) > 0 {
+		s.arguments = expressions[0]
+		s.expressions = expressions[1:]
+	}
+	ui.preselectedFiles = files
+}
+
 // Initialize UI
 func (ui *UI) InitUI() error {
 
-	go ui.App.QueueUpdateDraw(ui.evaluateExpression())
 	if len(ui.stdinTmpFile) > 0 {
 		ui.FileOptionsText.SetText(ui.stdinTmpFile)
 		ui.hideFileElements = true
@@ -757,11 +1143,22 @@ func (ui *UI) InitUI() error {
 	ui.configArgumentsInput()
 	ui.configArgumentsInputWide()
 	ui.configArgumentsInputWideFlex()
+	ui.configStatusText()
 	ui.configFileOptionsInput()
 	ui.configFileOptionsTreeNode()
 	ui.configFileOptionsTreeView()
 	ui.configOutputView()
+	ui.configChangesView()
+	ui.configCompareView()
+	ui.configVariablesView()
+	ui.configMatchesView()
+	ui.configBenchmarkView()
+	ui.configHelpView()
 	ui.configFileView()
+	ui.configEnvironmentInput()
+	ui.configEnvironmentFlex()
+	ui.configStageInput()
+	ui.configPipelineFlex()
 	ui.configChildFlex()
 	ui.configFlex()
 
@@ -770,36 +1167,83 @@ func (ui *UI) InitUI() error {
 		key := event.Key()
 		switch key {
 		case tcell.KeyCtrlS:
-			endOptionsSeparator, _, _, _ := ui.endOptionsSeparator()
-			endArgumentsSeparator, _, _, _ := ui.endArgumentsSeparator()
-
-			var sb strings.Builder
-			sb.WriteString(ui.Label)
-			sb.WriteString(" ")
-			sb.WriteString(ui.OptionsInput.GetText())
-			sb.WriteString(endOptionsSeparator.GetText(false))
-			sb.WriteString(ui.OpeningQuoteText.GetText(false))
-			sb.WriteString(ui.getActiveInputText())
-			sb.WriteString(ui.ClosingQuoteText.GetText(false))
+			commands := ui.buildPipeline()
+			commands[0].Files = ui.FileOptionsInputSlice
 			if ui.hideFileElements {
-				_ = os.Remove(ui.stdinTmpFile)
-			} else {
-				sb.WriteString(endArgumentsSeparator.GetText(false))
-				sb.WriteString(strings.Join(ui.FileOptionsInputSlice, " "))
+				ui.removeStdin()
 			}
+			ui.scheduler.stop()
 			ui.App.Stop()
-			fmt.Println(sb.String())
+			fmt.Println(program.PipelineString(commands))
+		case tcell.KeyCtrlP:
+			ui.applyChanges()
+			return nil
+		case tcell.KeyCtrlT:
+			ui.toggleEnvironment()
+			return nil
+		case tcell.KeyCtrlN:
+			if ui.canEditStages() {
+				ui.toggleStageInput(true)
+			}
+			return nil
+		case tcell.KeyCtrlG:
+			if ui.canEditStages() {
+				ui.selectStage((ui.selectedStage + 1) % len(ui.stages))
+			}
+			return nil
+		case tcell.KeyCtrlR:
+			if ui.canEditStages() {
+				ui.removeStage()
+			}
+			return nil
+		case tcell.KeyF1:
+			if ui.canEditStages() {
+				ui.showHelp()
+			}
+			return nil
+		case tcell.KeyF5:
+			if ui.canEditStages() {
+				ui.runBenchmark()
+			}
+			return nil
+		case tcell.KeyF6:
+			if ui.canEditStages() {
+				ui.pinBaseline()
+			}
+			return nil
+		case tcell.KeyF7:
+			if ui.followInterval > 0 && ui.stdin != nil {
+				ui.toggleFollow()
+			}
+			return nil
 		case tcell.KeyCtrlC:
+			if ui.benchmarkCancel != nil {
+				ui.benchmarkCancel()
+			}
+			if ui.helpCancel != nil {
+				ui.helpCancel()
+			}
+			ui.scheduler.stop()
 			if ui.hideFileElements {
-				_ = os.Remove(ui.stdinTmpFile)
+				ui.removeStdin()
 			}
 		}
 		return event
 	})
 
+	ui.App.SetAfterDrawFunc(ui.highlightDiagnostic)
+
+	ui.loadStage(0)
+	if !ui.hideFileElements {
+		ui.selectFiles(ui.preselectedFiles)
+	}
 	ui.ActiveFlex = &ui.Flex
-	ui.App.SetRoot(ui.Flex, true).
-		SetFocus(ui.OptionsInput)
+	ui.App.SetRoot(ui.Flex, true)
+	ui.focusCommandBar()
+	ui.scheduleEvaluation()
+	if ui.stdin != nil {
+		go ui.watchStdin()
+	}
 
 	return nil
 }
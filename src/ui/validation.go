@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+)
+
+// Returns the TextView used for the status line below the command bar
+func statusText() *tview.TextView {
+	return tview.NewTextView().
+		SetDynamicColors(true)
+}
+
+// Helper function to show the error of the expression of the given stage in the status line,
+// or to clear it from the status line if there is none
+func (ui *UI) showDiagnostic(stage int, d *program.Diagnostic) {
+	ui.diagnostic = d
+	ui.diagnosticStage = stage
+	ui.updateStatusText()
+}
+
+// Helper function to show the error of the expression, if any, followed by the state of the standard input
+func (ui *UI) updateStatusText() {
+	var lines []string
+	if d := ui.diagnostic; d != nil {
+		var sb strings.Builder
+		sb.WriteString(" " + colorTag(ui.Theme.ErrorColor))
+		if len(ui.stages) > 1 {
+			sb.WriteString(fmt.Sprintf("stage %d: ", ui.diagnosticStage+1))
+		}
+		sb.WriteString(tview.Escape(d.Message))
+		sb.WriteString("[-] (output of the last valid expression shown)")
+		lines = append(lines, sb.String())
+	}
+	if status := ui.stdinStatus(); len(status) > 0 {
+		lines = append(lines, status)
+	}
+	ui.StatusText.SetText(strings.Join(lines, "\n"))
+}
+
+// Helper function to highlight the position of the error of the expression in ArgumentsInput, once the screen is drawn.
+// The position is only known when the whole expression is visible.
+func (ui *UI) highlightDiagnostic(screen tcell.Screen) {
+	d := ui.diagnostic
+	if d == nil || ui.diagnosticStage != ui.selectedStage || ui.ActiveFlex != &ui.Flex || ui.fieldIndex(ui.ArgumentsInput) < 0 {
+		return
+	}
+	// the expression may have changed since it was validated
+	text := ui.ArgumentsInput.GetText()
+	if text != d.Expression || strings.Contains(text, "\n") {
+		return
+	}
+	x, y, width, height := ui.ArgumentsInput.GetInnerRect()
+	if height < 1 || tview.TaggedStringWidth(tview.Escape(text)) >= width {
+		return
+	}
+	column := x + tview.TaggedStringWidth(tview.Escape(text[:d.Offset]))
+	mainc, combc, style, _ := screen.GetContent(column, y)
+	screen.SetContent(column, y, mainc, combc, style.Foreground(ui.Theme.ErrorColor).Reverse(true))
+}
+
+// Function for configuring StatusText TextView
+func (ui *UI) configStatusText() {
+	ui.StatusText.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.StatusText.SetTextColor(ui.Theme.BorderColor)
+}
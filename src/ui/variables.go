@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	program "github.com/paololazzari/play/src/util"
+	"github.com/rivo/tview"
+)
+
+// Returns the TextView used for the variables of the program
+func variablesView() *tview.TextView {
+	t := tview.NewTextView().
+		SetDynamicColors(true)
+	t.SetBorder(true)
+	t.SetTitle(" Variables ")
+	return t
+}
+
+// Helper function returning whether the variables of the program played with are shown
+func (ui *UI) showsVariables() bool {
+	return ui.stages[0].program.ReportsVariables()
+}
+
+// Helper function for displaying the variables of the program of the selected stage, as of the end of its evaluation
+func (ui *UI) showVariables(res program.Result) {
+	if !ui.showsVariables() {
+		return
+	}
+	if !ui.stages[ui.selectedStage].program.ReportsVariables() {
+		ui.VariablesView.SetTitle(" Variables (not available for this stage) ")
+		ui.VariablesView.SetText("")
+		return
+	}
+
+	var sb strings.Builder
+	for _, variable := range res.Variables {
+		sb.WriteString(colorTag(ui.Theme.KeywordColor))
+		sb.WriteString(tview.Escape(variable.Name))
+		sb.WriteString("[-] = ")
+		sb.WriteString(tview.Escape(variable.Value))
+		sb.WriteString("\n")
+	}
+	ui.VariablesView.SetTitle(fmt.Sprintf(" Variables (%d) ", len(res.Variables)))
+	ui.VariablesView.SetText(sb.String())
+}
+
+// Function for configuring VariablesView TextView
+func (ui *UI) configVariablesView() {
+	ui.VariablesView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc, tcell.KeyTab, tcell.KeyBacktab:
+			ui.App.SetFocus(ui.OutputView)
+		}
+		return event
+	})
+
+	ui.VariablesView.SetBackgroundColor(ui.Theme.BackGroundColor)
+	ui.VariablesView.SetTitleColor(ui.Theme.KeywordColor)
+	ui.VariablesView.SetBorderColor(ui.Theme.BorderColor)
+	ui.VariablesView.SetTextColor(ui.Theme.BorderColor)
+}
@@ -0,0 +1,142 @@
+package program
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// Backend evaluating the commands of programs.
+// Programs are executed by the external backend unless they name the builtin engine evaluating them.
+type Backend interface {
+	// Returns the arguments of the given command, starting with the program
+	Args(command Command) ([]string, error)
+	// Returns the error in the expression of the given command, if it can be told without evaluating it.
+	// Validation only fails on errors in the expression, and gives up when it cannot tell.
+	Validate(ctx context.Context, command Command, settings Settings) *Diagnostic
+	// Evaluate the given command, reading its standard input from stdin if not nil.
+	// An error is returned if the context is done before the evaluation completes
+	// or if the command could not be evaluated at all.
+	Execute(ctx context.Context, command Command, settings Settings, stdin *string) (Result, error)
+	// Returns the help of the given program
+	Help(ctx context.Context, p Program, settings Settings) (Result, error)
+}
+
+// Backend able to highlight the expressions and the outputs of its commands
+type Highlighter interface {
+	// Returns the parts of the given expression to highlight
+	HighlightExpression(expression string) []Highlight
+	// Returns the parts of the output of the given result to highlight
+	HighlightOutput(res Result) []Highlight
+}
+
+// Backend able to complete the options of its commands
+type Completer interface {
+	// Returns the options of the given command starting with the given prefix
+	CompleteOption(command Command, prefix string) []string
+}
+
+// Part of a text to highlight, between the given byte offsets.
+// Parts of the same class are highlighted the same way, and highlights
+// take precedence over the ones preceding them.
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+	Class int `json:"class"`
+}
+
+// Returns the backend evaluating the program, or nil if its engine is unknown
+func (p Program) Backend() Backend {
+	return backend(p.Engine)
+}
+
+// Returns the backend evaluating the command, or nil if its engine is unknown
+func (c Command) Backend() Backend {
+	return backend(c.Engine)
+}
+
+// Helper function returning the backend of the engine of the given name, the external backend if empty
+func backend(engine string) Backend {
+	if len(engine) == 0 {
+		return externalBackend{}
+	}
+	if b, ok := backends[engine]; ok {
+		return b
+	}
+	return nil
+}
+
+// Backend executing the programs, either directly or through the shell
+type externalBackend struct{}
+
+func (externalBackend) Args(command Command) ([]string, error) {
+	return command.Args()
+}
+
+// Expressions are validated by the validator of the implementation of the program, if any.
+// In shell mode the options may hold anything, such as pipes, so expressions are not validated.
+func (externalBackend) Validate(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	if settings.Shell {
+		return nil
+	}
+	v, ok := validators[implementation(command)]
+	if !ok {
+		return nil
+	}
+	return v(ctx, command, settings)
+}
+
+// In scratch mode the command operates on copies of its files, and the
+// modifications made to them are returned in the result.
+func (externalBackend) Execute(ctx context.Context, command Command, settings Settings, stdin *string) (Result, error) {
+	var ws *workspace
+	if settings.Scratch && len(command.Files) > 0 {
+		var err error
+		ws, err = newWorkspace(command.Files)
+		if err != nil {
+			return Result{}, err
+		}
+		defer ws.remove()
+		command = ws.command(command)
+	}
+
+	scratchDir := ""
+	if ws != nil {
+		scratchDir = ws.dir
+	}
+	cmd, err := newProcess(command, settings, scratchDir)
+	if err != nil {
+		return Result{}, err
+	}
+	if stdin != nil {
+		cmd.Stdin = strings.NewReader(*stdin)
+	} else if command.FilePlacement == FilesOnStdin && !settings.Shell {
+		files, err := openFiles(command.Files)
+		if err != nil {
+			return Result{}, err
+		}
+		defer closeFiles(files)
+		cmd.Stdin = concatenate(files)
+	}
+	res, err := execute(ctx, cmd, settings)
+	if ctx.Err() != nil {
+		return Result{}, ctx.Err()
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if ws != nil {
+		res.Changes = ws.changes()
+	}
+	return res, nil
+}
+
+// The help is printed by the program with its help flag
+func (externalBackend) Help(ctx context.Context, p Program, settings Settings) (Result, error) {
+	flags, err := SplitOptions(p.HelpFlag)
+	if err != nil {
+		return Result{}, err
+	}
+	args := append(append([]string{p.Executable()}, p.PathArgs...), flags...)
+	return execute(ctx, exec.Command(args[0], args[1:]...), settings)
+}
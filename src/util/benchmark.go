@@ -0,0 +1,89 @@
+package program
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Statistics of the repeated evaluation of a pipeline.
+// Durations are the sum of the durations of the commands of the pipeline,
+// and the peak resident set size is 0 when unknown.
+type Benchmark struct {
+	Pipeline  string          `json:"pipeline"`
+	Runs      int             `json:"runs"`
+	Warmup    int             `json:"warmup"`
+	Mean      time.Duration   `json:"mean_ns"`
+	Median    time.Duration   `json:"median_ns"`
+	P95       time.Duration   `json:"p95_ns"`
+	PeakRSS   int64           `json:"peak_rss_bytes"`
+	Durations []time.Duration `json:"durations_ns"`
+}
+
+// Helper function to compute the statistics of the collected durations
+func (b *Benchmark) summarize() {
+	sorted := make([]time.Duration, len(b.Durations))
+	copy(sorted, b.Durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	n := len(sorted)
+	b.Mean = total / time.Duration(n)
+	if n%2 == 1 {
+		b.Median = sorted[n/2]
+	} else {
+		b.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	// nearest-rank percentile
+	b.P95 = sorted[(n*95+99)/100-1]
+}
+
+// Run the given pipelines the given number of times after some warmup runs, which are not measured.
+// The runs of the pipelines are interleaved so that they are equally affected by the load of the machine.
+// Results are never taken from the cache, and a run timing out fails the whole benchmark.
+func RunBenchmark(ctx context.Context, pipelines [][]Command, settings Settings, runs int, warmup int) ([]Benchmark, error) {
+	if runs <= 0 {
+		return nil, errors.New("the number of runs must be positive")
+	}
+	settings.Cache = nil
+
+	benchmarks := make([]Benchmark, len(pipelines))
+	for i, commands := range pipelines {
+		benchmarks[i] = Benchmark{
+			Pipeline: PipelineString(commands),
+			Runs:     runs,
+			Warmup:   warmup,
+		}
+	}
+
+	for run := 0; run < warmup+runs; run++ {
+		for i, commands := range pipelines {
+			results, err := RunPipeline(ctx, commands, settings)
+			if err != nil {
+				return nil, err
+			}
+			var duration time.Duration
+			for _, res := range results {
+				if res.TimedOut {
+					return nil, errors.New("evaluation timed out, consider increasing --timeout")
+				}
+				duration += res.Duration
+				if res.MaxRSS > benchmarks[i].PeakRSS {
+					benchmarks[i].PeakRSS = res.MaxRSS
+				}
+			}
+			if run >= warmup {
+				benchmarks[i].Durations = append(benchmarks[i].Durations, duration)
+			}
+		}
+	}
+
+	for i := range benchmarks {
+		benchmarks[i].summarize()
+	}
+	return benchmarks, nil
+}
@@ -0,0 +1,242 @@
+package program
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Maximum number of file hashes remembered by the cache
+const maxFileHashes = 1024
+
+// Coarsest granularity of the modification times of files: files modified more recently than that
+// may be modified again without their modification time changing, so their hashes are not remembered
+const mtimeGranularity = 2 * time.Second
+
+// Characters which make the shell read other files than the ones named in the options, such as globs
+const shellExpansions = "*?[~$`<>|;&(){}"
+
+// Short options of grep taking a value, such as -e PATTERNS
+const grepValueOptions = "efmABCdD"
+
+// Error returned for commands whose result depends on more than their key, such as
+// commands reading directories
+var errUncacheable = errors.New("command cannot be cached")
+
+// Bounded, least recently used cache of evaluation results.
+// Results are keyed by the normalized command, its environment and the state
+// of its input files, so that entries are invalidated when any of them changes.
+type Cache struct {
+	mu         sync.Mutex
+	capacity   int
+	entries    map[string]*list.Element
+	order      *list.List
+	fileHashes map[string]string
+}
+
+type cacheEntry struct {
+	key    string
+	result Result
+}
+
+// Cache constructor
+func NewCache(capacity int) *Cache {
+	return &Cache{
+		capacity:   capacity,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		fileHashes: make(map[string]string),
+	}
+}
+
+// Returns the cached result for the given key, if any
+func (c *Cache) get(key string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return Result{}, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).result, true
+}
+
+// Store the result for the given key, evicting the least recently used entry if needed
+func (c *Cache) put(key string, result Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).result = result
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, result})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Returns the fingerprint of the given file, made of its path, size, modification time and hash.
+// Hashes are remembered as long as the size and modification time do not change, unless the file was
+// just modified. Captured inputs only grow, so they are fingerprinted by their size and offset instead.
+func (c *Cache) fileFingerprint(file string) (string, error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	if capture, ok := captureOf(path); ok {
+		state := capture.State()
+		return fmt.Sprintf("%s capture %d %d", path, state.Discarded, state.Size), nil
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !stat.Mode().IsRegular() {
+		return "", errUncacheable
+	}
+	state := fmt.Sprintf("%s %d %d", path, stat.Size(), stat.ModTime().UnixNano())
+	remember := time.Since(stat.ModTime()) > mtimeGranularity
+
+	c.mu.Lock()
+	sum, ok := c.fileHashes[state]
+	c.mu.Unlock()
+	if ok {
+		return state + " " + sum, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum = hex.EncodeToString(h.Sum(nil))
+
+	if remember {
+		c.mu.Lock()
+		if len(c.fileHashes) >= maxFileHashes {
+			c.fileHashes = make(map[string]string)
+		}
+		c.fileHashes[state] = sum
+		c.mu.Unlock()
+	}
+	return state + " " + sum, nil
+}
+
+// Helper function to write a field of the key unambiguously
+func writeField(h hash.Hash, name string, value string) {
+	fmt.Fprintf(h, "%s %d %s\n", name, len(value), value)
+}
+
+// Returns the cache key of the given command.
+// Besides the input files, any option or word of the template naming an existing file (such as a
+// script passed to awk -f) is part of the key as well. Commands naming directories or other files
+// which are not regular files cannot be cached, nor commands whose options are expanded by the shell.
+func (c *Cache) key(command Command, settings Settings, stdin *string) (string, error) {
+	h := sha256.New()
+
+	if settings.Shell {
+		writeField(h, "script", command.script())
+	} else {
+		args, err := command.Args()
+		if err != nil {
+			return "", err
+		}
+		for _, arg := range args {
+			writeField(h, "arg", arg)
+		}
+	}
+
+	writeField(h, "placement", command.FilePlacement)
+	writeField(h, "engine", command.Engine)
+
+	env := append([]string(nil), command.Env...)
+	sort.Strings(env)
+	for _, variable := range env {
+		writeField(h, "env", variable)
+	}
+	writeField(h, "settings", fmt.Sprintf("%v %v %v %d", settings.Shell, settings.Sandbox, settings.Scratch, settings.MaxOutput))
+	if stdin != nil {
+		writeField(h, "stdin", *stdin)
+	}
+
+	files := append([]string(nil), command.Files...)
+	if settings.Shell && strings.ContainsAny(command.Options, shellExpansions) {
+		return "", errUncacheable
+	}
+	words, err := command.operands()
+	if err != nil {
+		return "", err
+	}
+	for _, word := range words {
+		if _, value, found := strings.Cut(word, "="); found {
+			word = value
+		}
+		stat, err := os.Stat(word)
+		if err != nil {
+			continue
+		}
+		if !stat.Mode().IsRegular() {
+			return "", errUncacheable
+		}
+		files = append(files, word)
+	}
+	if len(files) == 0 && readsWorkingDirectory(command.Program, words) {
+		return "", errUncacheable
+	}
+	for _, file := range files {
+		fingerprint, err := c.fileFingerprint(file)
+		if err != nil {
+			return "", err
+		}
+		writeField(h, "file", fingerprint)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns whether the given words are options of a recursive grep, which reads the working
+// directory when no file is given
+func readsWorkingDirectory(name string, words []string) bool {
+	switch strings.TrimSuffix(filepath.Base(name), ".exe") {
+	case "grep", "egrep", "fgrep":
+	default:
+		return false
+	}
+	for _, word := range words {
+		switch {
+		case word == "--":
+			return false
+		case word == "--recursive" || word == "--dereference-recursive":
+			return true
+		case strings.HasPrefix(word, "--") || !strings.HasPrefix(word, "-"):
+			continue
+		}
+		// short options can be combined, as in -rn, up to one taking a value
+		for _, r := range word[1:] {
+			if r == 'r' || r == 'R' {
+				return true
+			}
+			if strings.ContainsRune(grepValueOptions, r) {
+				break
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package program
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper function to create a file with the given content, last modified long enough ago for its hash to be remembered
+func writeOldFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "input.txt")
+	script := filepath.Join(dir, "script.awk")
+	writeOldFile(t, file, "a\n")
+	writeOldFile(t, script, "{ print }\n")
+
+	base := Command{Program: "grep", Options: "-i", Expression: "a", Files: []string{file}, EndOfOptions: true}
+	with := func(change func(c *Command)) Command {
+		c := base
+		change(&c)
+		return c
+	}
+	tests := []struct {
+		name     string
+		command  Command
+		settings Settings
+		same     bool
+	}{
+		{"same command", base, Settings{}, true},
+		{"environment", with(func(c *Command) { c.Env = []string{"B=2", "A=1"} }), Settings{}, false},
+		{"expression", with(func(c *Command) { c.Expression = "b" }), Settings{}, false},
+		{"options", with(func(c *Command) { c.Options = "-v" }), Settings{}, false},
+		{"files", with(func(c *Command) { c.Files = nil }), Settings{}, false},
+		{"engine", with(func(c *Command) { c.Engine = "gojq" }), Settings{}, false},
+		{"shell", base, Settings{Shell: true}, false},
+		{"max output", base, Settings{MaxOutput: 10}, false},
+		{"script", with(func(c *Command) { c.Options = "-f " + script }), Settings{}, false},
+	}
+	cache := NewCache(8)
+	want, err := cache.key(base, Settings{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cache.key(tt.command, tt.settings, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == want) != tt.same {
+				t.Errorf("key() same = %v, want %v", got == want, tt.same)
+			}
+		})
+	}
+
+	ordered := with(func(c *Command) { c.Env = []string{"A=1", "B=2"} })
+	reversed := with(func(c *Command) { c.Env = []string{"B=2", "A=1"} })
+	k1, _ := cache.key(ordered, Settings{}, nil)
+	k2, _ := cache.key(reversed, Settings{}, nil)
+	if k1 != k2 {
+		t.Errorf("key() depends on the order of the environment")
+	}
+}
+
+func TestCacheKeyFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "input.txt")
+	writeOldFile(t, file, "aaaa\n")
+	command := Command{Program: "grep", Expression: "a", Files: []string{file}}
+	cache := NewCache(8)
+	before, err := cache.key(command, Settings{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a recently modified file is hashed again on every key
+	if err := os.WriteFile(file, []byte("cccc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	recent, err := cache.key(command, Settings{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recent == before {
+		t.Errorf("key() did not change after the file was modified")
+	}
+	stat, _ := os.Stat(file)
+	if err := os.WriteFile(file, []byte("dddd\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(file, stat.ModTime(), stat.ModTime())
+	if again, _ := cache.key(command, Settings{}, nil); again == recent {
+		t.Errorf("key() did not change after a same size edit of a recently modified file")
+	}
+}
+
+func TestCacheKeyUncacheable(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		command  Command
+		settings Settings
+	}{
+		{"directory option", Command{Program: "grep", Options: "-r", Expression: "a", Files: []string{dir}}, Settings{}},
+		{"directory word", Command{Program: "grep", Options: "-r " + dir, Expression: "a"}, Settings{}},
+		{"directory in template", Command{Program: "find", Template: dir + " -name {expr}", Expression: "*.txt"}, Settings{}},
+		{"device", Command{Program: "awk", Options: "-f " + os.DevNull, Expression: ""}, Settings{}},
+		{"missing file", Command{Program: "grep", Expression: "a", Files: []string{filepath.Join(dir, "missing")}}, Settings{}},
+		{"recursive grep", Command{Program: "grep", Options: "-r", Expression: "a"}, Settings{}},
+		{"combined recursive grep", Command{Program: "/usr/bin/grep", Options: "-inR", Expression: "a"}, Settings{}},
+		{"long recursive grep", Command{Program: "grep", Options: "--recursive", Expression: "a"}, Settings{}},
+		{"glob", Command{Program: "grep", Options: "-h *.txt", Expression: "a"}, Settings{Shell: true}},
+	}
+	cache := NewCache(8)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key, err := cache.key(tt.command, tt.settings, nil); err == nil {
+				t.Errorf("key() = %s, want an error", key)
+			}
+		})
+	}
+}
+
+func TestCacheKeyCapture(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	capture, err := CaptureInput(r, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(capture.File)
+	command := Command{Program: "grep", Expression: "a", Files: []string{capture.File}}
+	cache := NewCache(8)
+
+	w.WriteString(strings.Repeat("a\n", 10))
+	waitForSize(t, capture, 20)
+	first, err := cache.key(command, Settings{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("b\n")
+	w.Close()
+	<-capture.Done()
+	second, err := cache.key(command, Settings{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("key() did not change as the captured input grew")
+	}
+}
+
+// Helper function to wait until the capture holds the given number of bytes
+func waitForSize(t *testing.T, c *Capture, size int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.State().Size < size {
+		if time.Now().After(deadline) {
+			t.Fatalf("capture holds %d bytes, want %d", c.State().Size, size)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,305 @@
+package program
+
+import (
+	"errors"
+	"strings"
+)
+
+// Command to be evaluated.
+// Path and PathArgs, when set, replace the program name when executing the command.
+// The files are passed as arguments unless FilePlacement is FilesOnStdin.
+// When set, Template gives the arguments following the program name: the words
+// {options}, {expr1}, {expr2}... and {files} stand for the options, the expressions
+// and the files, and any other word is passed as is, e.g. "{options} -e {expr} -- {files}".
+// Expression is the first expression, and ExtraExpressions the following ones.
+// When set, Engine is the name of the builtin engine evaluating the command.
+type Command struct {
+	Program          string
+	Path             string
+	PathArgs         []string
+	Options          string
+	Expression       string
+	ExtraExpressions []string
+	Quote            string
+	Files            []string
+	FilePlacement    string
+	EndOfOptions     bool
+	Env              []string
+	Template         string
+	Engine           string
+}
+
+// Split the options into words the same way a POSIX shell would,
+// honouring single quotes, double quotes and backslash escapes
+func SplitOptions(options string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	quote := rune(0)
+	escaped := false
+
+	for _, r := range options {
+		switch {
+		case escaped:
+			// inside double quotes a backslash only escapes a few characters
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' {
+				escaped = true
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote in command options")
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash in command options")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// Helper function to quote a word for the shell if needed
+func shellQuote(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\n'\"\\$`!*?[](){}<>|&;#~") {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// Join the given words into command options, quoting them so that SplitOptions returns them as they are
+func JoinOptions(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = shellQuote(word)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Returns the argument vector of the command, program name included
+func (c Command) Args() ([]string, error) {
+	options, err := SplitOptions(c.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{c.Program}
+	if len(c.Path) > 0 {
+		args = append([]string{c.Path}, c.PathArgs...)
+	}
+	if len(c.Template) > 0 {
+		parts, err := ParseTemplate(c.Template)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			switch part.Kind {
+			case TemplateOptions:
+				args = append(args, options...)
+			case TemplateExpression:
+				args = append(args, c.expression(part.Index))
+			case TemplateFiles:
+				if c.FilePlacement != FilesOnStdin {
+					args = append(args, c.Files...)
+				}
+			default:
+				args = append(args, part.Word)
+			}
+		}
+		return args, nil
+	}
+	args = append(args, options...)
+	if c.EndOfOptions {
+		args = append(args, "--")
+	}
+	args = append(args, c.Expression)
+	if c.FilePlacement == FilesOnStdin {
+		return args, nil
+	}
+	if !c.EndOfOptions && len(c.Files) > 0 {
+		args = append(args, "--")
+	}
+	args = append(args, c.Files...)
+	return args, nil
+}
+
+// Returns the words of the options and the literal words of the template of the command,
+// which may name files read by the program
+func (c Command) operands() ([]string, error) {
+	words, err := SplitOptions(c.Options)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Template) > 0 {
+		parts, err := ParseTemplate(c.Template)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			if part.Kind == TemplateLiteral {
+				words = append(words, part.Word)
+			}
+		}
+	}
+	return words, nil
+}
+
+// Returns the command as it would be typed in a shell
+func (c Command) String() string {
+	var sb strings.Builder
+	sb.WriteString(c.stdinPrefix())
+	for _, variable := range c.Env {
+		name, value, _ := strings.Cut(variable, "=")
+		sb.WriteString(name)
+		sb.WriteString("=")
+		sb.WriteString(shellQuote(value))
+		sb.WriteString(" ")
+	}
+	sb.WriteString(c.invocation())
+	return sb.String()
+}
+
+// Returns the command without its environment variables, as evaluated in shell mode
+func (c Command) script() string {
+	return c.stdinPrefix() + c.invocation()
+}
+
+// Returns the part of the command feeding the files on standard input, if needed
+func (c Command) stdinPrefix() string {
+	if c.FilePlacement != FilesOnStdin || len(c.Files) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("cat")
+	for _, file := range c.Files {
+		sb.WriteString(" ")
+		sb.WriteString(shellQuote(file))
+	}
+	sb.WriteString(" | ")
+	return sb.String()
+}
+
+// Returns the invocation of the program itself
+func (c Command) invocation() string {
+	var sb strings.Builder
+	if len(c.Path) > 0 {
+		sb.WriteString(shellQuote(c.Path))
+		for _, arg := range c.PathArgs {
+			sb.WriteString(" ")
+			sb.WriteString(shellQuote(arg))
+		}
+	} else {
+		sb.WriteString(c.Program)
+	}
+	if len(c.Template) > 0 {
+		// invalid templates are reported when the arguments are built
+		parts, _ := ParseTemplate(c.Template)
+		for _, part := range parts {
+			switch part.Kind {
+			case TemplateOptions:
+				if len(c.Options) > 0 {
+					sb.WriteString(" ")
+					sb.WriteString(c.Options)
+				}
+			case TemplateExpression:
+				sb.WriteString(" ")
+				sb.WriteString(c.quote(c.expression(part.Index)))
+			case TemplateFiles:
+				if c.FilePlacement != FilesOnStdin {
+					for _, file := range c.Files {
+						sb.WriteString(" ")
+						sb.WriteString(shellQuote(file))
+					}
+				}
+			default:
+				sb.WriteString(" ")
+				sb.WriteString(shellQuote(part.Word))
+			}
+		}
+		return sb.String()
+	}
+	if len(c.Options) > 0 {
+		sb.WriteString(" ")
+		sb.WriteString(c.Options)
+	}
+	if c.EndOfOptions {
+		sb.WriteString(" --")
+	}
+	sb.WriteString(" ")
+	sb.WriteString(c.quote(c.Expression))
+	if c.FilePlacement == FilesOnStdin {
+		return sb.String()
+	}
+	if !c.EndOfOptions && len(c.Files) > 0 {
+		sb.WriteString(" --")
+	}
+	for _, file := range c.Files {
+		sb.WriteString(" ")
+		sb.WriteString(shellQuote(file))
+	}
+	return sb.String()
+}
+
+// Returns the pipeline of the given commands as it would be typed in a shell
+func PipelineString(commands []Command) string {
+	var parts []string
+	for _, command := range commands {
+		parts = append(parts, command.String())
+	}
+	return strings.Join(parts, " | ")
+}
+
+// Returns the expression with the given index, from 0
+func (c Command) expression(i int) string {
+	if i == 0 {
+		return c.Expression
+	}
+	if i-1 < len(c.ExtraExpressions) {
+		return c.ExtraExpressions[i-1]
+	}
+	return ""
+}
+
+// Returns the given expression within the quotes of the command
+func (c Command) quote(expression string) string {
+	switch c.Quote {
+	case "'":
+		return "'" + strings.ReplaceAll(expression, "'", `'\''`) + "'"
+	case `"`:
+		return `"` + strings.ReplaceAll(expression, `"`, `\"`) + `"`
+	default:
+		return expression
+	}
+}
@@ -0,0 +1,63 @@
+package program
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitOptions(t *testing.T) {
+	tests := []struct {
+		options string
+		want    []string
+		wantErr bool
+	}{
+		{options: "", want: nil},
+		{options: "  -i  -n ", want: []string{"-i", "-n"}},
+		{options: "-F ' '", want: []string{"-F", " "}},
+		{options: `-e "a b" -e 'c d'`, want: []string{"-e", "a b", "-e", "c d"}},
+		{options: `-e a\ b\`, wantErr: true},
+		{options: `-e a\\ b`, want: []string{"-e", `a\`, "b"}},
+		{options: `-e a\ b`, want: []string{"-e", "a b"}},
+		{options: `"a\"b" 'a\b'`, want: []string{`a"b`, `a\b`}},
+		{options: `"\$HOME \n"`, want: []string{`$HOME \n`}},
+		{options: `''`, want: []string{""}},
+		{options: `--regexp='it'\''s'`, want: []string{"--regexp=it's"}},
+		{options: "-a\tb\n-c", want: []string{"-a", "b", "-c"}},
+		{options: "'unterminated", wantErr: true},
+		{options: `"unterminated`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.options, func(t *testing.T) {
+			got, err := SplitOptions(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitOptions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinOptions(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"-E", "-n"}, "-E -n"},
+		{[]string{"-F", " "}, "-F ' '"},
+		{[]string{"-e", "it's"}, `-e 'it'\''s'`},
+		{[]string{""}, "''"},
+		{[]string{"--regexp=$a|b"}, "'--regexp=$a|b'"},
+	}
+	for _, tt := range tests {
+		got := JoinOptions(tt.words)
+		if got != tt.want {
+			t.Errorf("JoinOptions(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+		if words, err := SplitOptions(got); err != nil || len(words) != len(tt.words) || (len(words) > 0 && !reflect.DeepEqual(words, tt.words)) {
+			t.Errorf("SplitOptions(%q) = %q, %v, want %q", got, words, err, tt.words)
+		}
+	}
+}
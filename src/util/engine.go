@@ -0,0 +1,166 @@
+package program
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Evaluator of commands within play itself, without spawning a process.
+// The engine reads its input from the files of the command or, if it has none, from stdin,
+// and sets in the result the exit status the program would have, along with the location
+// of the error in the expression if the expression is invalid.
+// In sandbox and scratch mode, the engine must not modify files nor execute programs.
+type engine func(ctx context.Context, command Command, settings Settings, stdin io.Reader, stdout io.Writer, stderr io.Writer, res *Result)
+
+// Backend evaluating commands with a builtin engine.
+// Options are the options supported by the engine, and variables and matches tell whether the engine
+// reports the variables of the programs it evaluates or the matches of the expressions it evaluates.
+type engineBackend struct {
+	evaluate  engine
+	validate  func(command Command) *Diagnostic
+	help      string
+	options   []string
+	variables bool
+	matches   bool
+}
+
+// Backends of the builtin engines, by name
+var backends = map[string]Backend{
+	"gojq": &engineBackend{
+		evaluate: gojqEngine,
+		validate: validateJq,
+		help:     jqHelp,
+		options:  jqOptionNames,
+	},
+	"goawk": &engineBackend{
+		evaluate:  goawkEngine,
+		validate:  validateAwk,
+		help:      awkHelp,
+		options:   awkOptionNames,
+		variables: true,
+	},
+	"regexp": regexBackend{&engineBackend{
+		evaluate: regexpEngine,
+		validate: validateRegex,
+		help:     regexHelp,
+		options:  regexOptionNames,
+		matches:  true,
+	}},
+}
+
+// Programs which can be evaluated by a builtin engine, and the name of that engine
+var builtinEngines = map[string]string{
+	"jq":    "gojq",
+	"awk":   "goawk",
+	"regex": "regexp",
+}
+
+// Programs which only exist as builtin engines
+var builtinOnly = map[string]bool{
+	"regex": true,
+}
+
+// Returns the name of the builtin engine able to evaluate the given program, if any
+func BuiltinEngine(program string) (string, bool) {
+	name, ok := builtinEngines[program]
+	return name, ok
+}
+
+// Returns whether the given program only exists as a builtin engine
+func BuiltinOnly(program string) bool {
+	return builtinOnly[program]
+}
+
+// Returns the program evaluated by its builtin engine
+func (p Program) WithBuiltinEngine() (Program, error) {
+	name, ok := BuiltinEngine(p.Name)
+	if !ok {
+		return p, fmt.Errorf("%s has no builtin engine", p.Name)
+	}
+	p.Engine = name
+	p.Path = ""
+	p.PathArgs = nil
+	return p, nil
+}
+
+// Helper function returning the builtin engine of the given backend, if any
+func engineOf(b Backend) (*engineBackend, bool) {
+	switch e := b.(type) {
+	case *engineBackend:
+		return e, true
+	case regexBackend:
+		return e.engineBackend, true
+	}
+	return nil, false
+}
+
+// Returns whether the variables of the program are reported after each evaluation
+func (p Program) ReportsVariables() bool {
+	e, ok := engineOf(p.Backend())
+	return ok && e.variables
+}
+
+// Returns whether the matches of the expression are reported after each evaluation
+func (p Program) ReportsMatches() bool {
+	e, ok := engineOf(p.Backend())
+	return ok && e.matches
+}
+
+// The arguments of the command are only shown, as the command is not executed
+func (e *engineBackend) Args(command Command) ([]string, error) {
+	return command.Args()
+}
+
+func (e *engineBackend) Validate(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	return e.validate(command)
+}
+
+// The evaluation is cancelled when the context is done, when the timeout
+// expires or when the output exceeds the maximum size.
+func (e *engineBackend) Execute(ctx context.Context, command Command, settings Settings, stdin *string) (Result, error) {
+	timeoutCtx := ctx
+	if settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		timeoutCtx, cancel = context.WithTimeout(ctx, settings.Timeout)
+		defer cancel()
+	}
+	killCtx, kill := context.WithCancel(timeoutCtx)
+	defer kill()
+
+	stdout := &cappedBuffer{limit: settings.MaxOutput, onTruncate: kill}
+	stderr := &cappedBuffer{limit: settings.MaxOutput, onTruncate: kill}
+	input := strings.NewReader("")
+	if stdin != nil {
+		input = strings.NewReader(*stdin)
+	}
+
+	start := time.Now()
+	var res Result
+	e.evaluate(killCtx, command, settings, input, stdout, stderr, &res)
+	if ctx.Err() != nil {
+		return Result{}, ctx.Err()
+	}
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+	res.Duration = time.Since(start)
+	res.Truncated = stdout.truncated || stderr.truncated
+	res.TimedOut = timeoutCtx.Err() == context.DeadlineExceeded
+	return res, nil
+}
+
+func (e *engineBackend) Help(ctx context.Context, p Program, settings Settings) (Result, error) {
+	return Result{Stdout: e.help}, nil
+}
+
+func (e *engineBackend) CompleteOption(command Command, prefix string) []string {
+	var options []string
+	for _, option := range e.options {
+		if strings.HasPrefix(option, prefix) {
+			options = append(options, option)
+		}
+	}
+	return options
+}
@@ -0,0 +1,320 @@
+package program
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benhoyt/goawk/interp"
+	"github.com/benhoyt/goawk/lexer"
+	"github.com/benhoyt/goawk/parser"
+)
+
+// Exit status of awk on usage and runtime errors
+const awkExitError = 2
+
+// Help of the goawk engine
+const awkHelp = `Usage: awk [OPTIONS] PROGRAM [FILES...]
+
+awk evaluated by the builtin goawk engine.
+
+Supported options:
+  -F fs          use fs as the input field separator
+  -v var=value   set the variable var to value before running the program
+
+The variables of the program are shown after each evaluation.
+`
+
+// Names of the options of awk supported by the goawk engine
+var awkOptionNames = []string{"-F", "-v"}
+
+// Array in which the values of the variables of the program are collected
+const awkVariablesArray = "__play_variables"
+
+// Maximum number of elements of each array shown in the variables of the program
+const maxArrayElements = 50
+
+// Special variables of awk, left out of the variables of the program
+var awkSpecialVariables = map[string]bool{
+	"ARGC": true, "ARGV": true, "CONVFMT": true, "ENVIRON": true, "FILENAME": true,
+	"FNR": true, "FS": true, "INPUTMODE": true, "NF": true, "NR": true, "OFMT": true,
+	"OFS": true, "ORS": true, "OUTPUTMODE": true, "RLENGTH": true, "RS": true,
+	"RSTART": true, "RT": true, "SUBSEP": true,
+}
+
+// Helper function to parse the options of awk into the name-value pairs of the variables they set
+func parseAwkOptions(words []string) ([]string, error) {
+	var vars []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		var name, value string
+		switch {
+		case word == "-F" || word == "-v":
+			if i+1 >= len(words) {
+				return nil, fmt.Errorf("flag needs an argument: %s", word)
+			}
+			name, value = word, words[i+1]
+			i++
+		case strings.HasPrefix(word, "-F") || strings.HasPrefix(word, "-v"):
+			name, value = word[:2], word[2:]
+		default:
+			return nil, fmt.Errorf("unsupported option %s", word)
+		}
+
+		if name == "-F" {
+			vars = append(vars, "FS", value)
+			continue
+		}
+		equals := strings.IndexByte(value, '=')
+		if equals < 0 {
+			return nil, fmt.Errorf("-v flag must be in format name=value")
+		}
+		// like awk, values given with -v are unescaped
+		unescaped, err := lexer.Unescape(value[equals+1:])
+		if err != nil {
+			unescaped = value[equals+1:]
+		}
+		vars = append(vars, value[:equals], unescaped)
+	}
+	return vars, nil
+}
+
+// Returns the diagnostic of the given error of the parser of goawk
+func awkDiagnostic(expression string, err error) *Diagnostic {
+	var parseError *parser.ParseError
+	if !errors.As(err, &parseError) {
+		return &Diagnostic{Message: err.Error(), Expression: expression, Offset: len(expression)}
+	}
+	// the position is given as a line and a byte column, both starting at 1
+	offset := 0
+	for line := 1; line < parseError.Position.Line; line++ {
+		i := strings.IndexByte(expression[offset:], '\n')
+		if i < 0 {
+			break
+		}
+		offset += i + 1
+	}
+	offset += parseError.Position.Column - 1
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(expression) {
+		offset = len(expression)
+	}
+	return &Diagnostic{Message: parseError.Message, Expression: expression, Offset: offset}
+}
+
+// Returns the error in the awk program of the given command, if any
+func validateAwk(command Command) *Diagnostic {
+	if _, err := parser.ParseProgram([]byte(command.Expression), nil); err != nil {
+		return awkDiagnostic(command.Expression, err)
+	}
+	return nil
+}
+
+// Returns the names of the variables used by the given awk program, other than the special ones
+func awkVariableNames(source string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	l := lexer.NewLexer([]byte(source))
+	previous := lexer.ILLEGAL
+	for {
+		_, tok, value := l.Scan()
+		if tok == lexer.EOF || tok == lexer.ILLEGAL {
+			break
+		}
+		switch {
+		case tok == lexer.DIV || tok == lexer.DIV_ASSIGN:
+			// a slash which cannot follow an operand starts a regex
+			if !awkEndsOperand(previous) {
+				l.ScanRegex()
+				tok = lexer.REGEX
+			}
+		case tok == lexer.NAME:
+			function := previous == lexer.FUNCTION || l.PeekByte() == '('
+			if !function && !awkSpecialVariables[value] && !seen[value] {
+				seen[value] = true
+				names = append(names, value)
+			}
+		}
+		previous = tok
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns whether the given token can end an operand, in which case a following slash is a division
+func awkEndsOperand(tok lexer.Token) bool {
+	switch tok {
+	case lexer.NAME, lexer.NUMBER, lexer.STRING, lexer.REGEX, lexer.RPAREN, lexer.RBRACKET, lexer.INCR, lexer.DECR:
+		return true
+	}
+	return tok >= lexer.FIRST_FUNC && tok <= lexer.LAST_FUNC
+}
+
+// Returns the given awk program followed by an action collecting the values of the given scalar variables
+func awkCollectingVariables(source string, program *parser.Program, scalars []string) string {
+	var sb strings.Builder
+	sb.WriteString(source)
+	// an END action would make programs made of BEGIN actions only read their input
+	if len(program.Actions) == 0 && len(program.End) == 0 {
+		sb.WriteString("\nBEGIN {")
+	} else {
+		sb.WriteString("\nEND {")
+	}
+	for _, name := range scalars {
+		fmt.Fprintf(&sb, " %s[\"%s\"] = %s;", awkVariablesArray, name, name)
+	}
+	sb.WriteString(" }\n")
+	return sb.String()
+}
+
+// Helper function to turn the given environment variables in name=value form into name-value pairs
+func awkEnviron(env []string) []string {
+	environ := make([]string, 0, 2*len(env))
+	for _, variable := range env {
+		if equals := strings.IndexByte(variable, '='); equals > 0 {
+			environ = append(environ, variable[:equals], variable[equals+1:])
+		}
+	}
+	return environ
+}
+
+// Helper function to format a value of goawk the way awk prints it
+func formatAwkValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e16 {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'g', 6, 64)
+	case string:
+		return strconv.Quote(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// Helper function returning the non-empty variables of the program evaluated by the given interpreter
+func awkVariables(interpreter *interp.Interpreter, scalars []string, arrays []string) []Variable {
+	var variables []Variable
+	values := interpreter.Array(awkVariablesArray)
+	for _, name := range scalars {
+		if value, ok := values[name]; ok && value != "" {
+			variables = append(variables, Variable{Name: name, Value: formatAwkValue(value)})
+		}
+	}
+
+	for _, name := range arrays {
+		elements := interpreter.Array(name)
+		keys := make([]string, 0, len(elements))
+		for key := range elements {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			if i == maxArrayElements {
+				variables = append(variables, Variable{
+					Name:  name + "[...]",
+					Value: fmt.Sprintf("%d more elements", len(keys)-maxArrayElements),
+				})
+				break
+			}
+			variables = append(variables, Variable{
+				Name:  fmt.Sprintf("%s[%s]", name, strconv.Quote(key)),
+				Value: formatAwkValue(elements[key]),
+			})
+		}
+	}
+	return variables
+}
+
+// Engine evaluating awk programs with goawk
+func goawkEngine(ctx context.Context, command Command, settings Settings, stdin io.Reader, stdout io.Writer, stderr io.Writer, res *Result) {
+	words, err := SplitOptions(command.Options)
+	var vars []string
+	if err == nil {
+		vars, err = parseAwkOptions(words)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "awk: %s\n", err)
+		res.ExitCode = awkExitError
+		return
+	}
+
+	program, err := parser.ParseProgram([]byte(command.Expression), nil)
+	if err != nil {
+		res.Diagnostic = awkDiagnostic(command.Expression, err)
+		fmt.Fprintf(stderr, "awk: %s\n", err)
+		res.ExitCode = awkExitError
+		return
+	}
+
+	// the values of the scalar variables are collected at the end of the program into an array,
+	// as goawk only gives access to the arrays of the programs it evaluates
+	original, err := interp.New(program)
+	if err != nil {
+		fmt.Fprintf(stderr, "awk: %s\n", err)
+		res.ExitCode = awkExitError
+		return
+	}
+	var scalars, arrays []string
+	for _, name := range awkVariableNames(command.Expression) {
+		if original.Array(name) != nil {
+			arrays = append(arrays, name)
+		} else {
+			scalars = append(scalars, name)
+		}
+	}
+	interpreter := original
+	collecting, err := parser.ParseProgram([]byte(awkCollectingVariables(command.Expression, program, scalars)), nil)
+	if err == nil {
+		if interpreter, err = interp.New(collecting); err != nil {
+			interpreter = original
+		}
+	}
+
+	config := &interp.Config{
+		Stdin:   stdin,
+		Output:  stdout,
+		Error:   stderr,
+		Argv0:   "awk",
+		Args:    command.Files,
+		Vars:    vars,
+		Environ: awkEnviron(append(os.Environ(), command.Env...)),
+	}
+	// like the sandbox, programs cannot modify files nor execute other programs in sandbox and scratch mode;
+	// in sandbox mode, they cannot read files either, so the input files are read by play itself
+	if settings.Sandbox || settings.Scratch {
+		config.NoExec = true
+		config.NoFileWrites = true
+	}
+	if settings.Sandbox {
+		config.NoFileReads = true
+		if len(command.Files) > 0 {
+			files, err := openFiles(command.Files)
+			if err != nil {
+				fmt.Fprintf(stderr, "awk: %s\n", err)
+				res.ExitCode = awkExitError
+				return
+			}
+			defer closeFiles(files)
+			config.Stdin = concatenate(files)
+			config.Args = nil
+		}
+	}
+	res.ExitCode, err = interpreter.ExecuteContext(ctx, config)
+	if err != nil {
+		if ctx.Err() == nil {
+			fmt.Fprintf(stderr, "awk: %s\n", err)
+		}
+		res.ExitCode = awkExitError
+		return
+	}
+	res.Variables = awkVariables(interpreter, scalars, arrays)
+}
@@ -0,0 +1,313 @@
+package program
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// Exit statuses of jq
+const (
+	jqExitFalsy        = 1
+	jqExitUsage        = 2
+	jqExitCompileError = 3
+	jqExitNoOutput     = 4
+	jqExitError        = 5
+)
+
+// Help of the gojq engine
+const jqHelp = `Usage: jq [OPTIONS] FILTER [FILES...]
+
+jq evaluated by the builtin gojq engine.
+
+Supported options:
+  -r, --raw-output          output strings without quotes
+  -j, --join-output         like -r, without a newline after each output
+  -c, --compact-output      compact output
+  -n, --null-input          use null as the single input
+  -s, --slurp               read all inputs into an array
+  -e, --exit-status         set the exit status from the last output
+  -S, --sort-keys           sort the keys of objects (always enabled)
+      --tab                 indent with tabs
+      --indent n            indent with n spaces
+      --arg name value      set $name to the string value
+      --argjson name value  set $name to the JSON value
+`
+
+// Names of the options of jq supported by the gojq engine
+var jqOptionNames = []string{
+	"-r", "--raw-output", "-j", "--join-output", "-c", "--compact-output", "-n", "--null-input",
+	"-s", "--slurp", "-e", "--exit-status", "-S", "--sort-keys", "--tab", "--indent", "--arg", "--argjson",
+}
+
+// Options of jq supported by the gojq engine
+type jqOptions struct {
+	raw        bool
+	join       bool
+	compact    bool
+	nullInput  bool
+	slurp      bool
+	exitStatus bool
+	indent     string
+	names      []string
+	values     []any
+}
+
+// Helper function to parse the options of jq
+func parseJqOptions(words []string) (jqOptions, error) {
+	options := jqOptions{indent: "  "}
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		// the value of the option following the current one, if any
+		next := func(n int) ([]string, error) {
+			if i+n >= len(words) {
+				return nil, fmt.Errorf("%s takes %d parameters", word, n)
+			}
+			values := words[i+1 : i+1+n]
+			i += n
+			return values, nil
+		}
+		switch word {
+		case "--raw-output":
+			options.raw = true
+		case "--join-output":
+			options.raw, options.join = true, true
+		case "--compact-output":
+			options.compact = true
+		case "--null-input":
+			options.nullInput = true
+		case "--slurp":
+			options.slurp = true
+		case "--exit-status":
+			options.exitStatus = true
+		case "--sort-keys":
+			// keys are always sorted
+		case "--tab":
+			options.indent = "\t"
+		case "--indent":
+			values, err := next(1)
+			if err != nil {
+				return options, err
+			}
+			n, err := strconv.Atoi(values[0])
+			if err != nil || n < 0 || n > 7 {
+				return options, fmt.Errorf("invalid indent %s", values[0])
+			}
+			options.indent = strings.Repeat(" ", n)
+		case "--arg":
+			values, err := next(2)
+			if err != nil {
+				return options, err
+			}
+			options.names = append(options.names, "$"+values[0])
+			options.values = append(options.values, values[1])
+		case "--argjson":
+			values, err := next(2)
+			if err != nil {
+				return options, err
+			}
+			var value any
+			decoder := json.NewDecoder(strings.NewReader(values[1]))
+			decoder.UseNumber()
+			if err := decoder.Decode(&value); err != nil {
+				return options, fmt.Errorf("invalid JSON text passed to --argjson")
+			}
+			options.names = append(options.names, "$"+values[0])
+			options.values = append(options.values, value)
+		default:
+			if !strings.HasPrefix(word, "-") || strings.HasPrefix(word, "--") || len(word) == 1 {
+				return options, fmt.Errorf("unsupported option %s", word)
+			}
+			// short options can be combined, as in -rc
+			for _, letter := range word[1:] {
+				switch letter {
+				case 'r':
+					options.raw = true
+				case 'j':
+					options.raw, options.join = true, true
+				case 'c':
+					options.compact = true
+				case 'n':
+					options.nullInput = true
+				case 's':
+					options.slurp = true
+				case 'e':
+					options.exitStatus = true
+				case 'S':
+				default:
+					return options, fmt.Errorf("unsupported option -%c", letter)
+				}
+			}
+		}
+	}
+	return options, nil
+}
+
+// Returns the diagnostic of the given error of the parser of gojq
+func jqDiagnostic(expression string, err error) *Diagnostic {
+	offset := len(expression)
+	var tokenError interface{ Token() (string, int) }
+	if errors.As(err, &tokenError) {
+		token, end := tokenError.Token()
+		offset = end - len(token)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(expression) {
+		offset = len(expression)
+	}
+	return &Diagnostic{Message: err.Error(), Expression: expression, Offset: offset}
+}
+
+// Returns the error in the jq expression of the given command, if any
+func validateJq(command Command) *Diagnostic {
+	if _, err := gojq.Parse(command.Expression); err != nil {
+		return jqDiagnostic(command.Expression, err)
+	}
+	return nil
+}
+
+// Helper function to write a value the way jq does
+func writeJqValue(w io.Writer, value any, options jqOptions) error {
+	if s, ok := value.(string); ok && options.raw {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+	data, err := gojq.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if !options.compact {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", options.indent); err != nil {
+			return err
+		}
+		data = indented.Bytes()
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Engine evaluating jq expressions with gojq
+func gojqEngine(ctx context.Context, command Command, settings Settings, stdin io.Reader, stdout io.Writer, stderr io.Writer, res *Result) {
+	words, err := SplitOptions(command.Options)
+	if err == nil {
+		var options jqOptions
+		if options, err = parseJqOptions(words); err == nil {
+			res.ExitCode, res.Diagnostic = evaluateJq(ctx, command, options, stdin, stdout, stderr)
+			return
+		}
+	}
+	fmt.Fprintf(stderr, "jq: error: %s\n", err)
+	res.ExitCode = jqExitUsage
+}
+
+// Helper function to evaluate the jq expression of the given command with the given options
+func evaluateJq(ctx context.Context, command Command, options jqOptions, stdin io.Reader, stdout io.Writer, stderr io.Writer) (int, *Diagnostic) {
+	query, err := gojq.Parse(command.Expression)
+	if err != nil {
+		diagnostic := jqDiagnostic(command.Expression, err)
+		fmt.Fprintf(stderr, "jq: error: %s at offset %d\njq: 1 compile error\n", err, diagnostic.Offset)
+		return jqExitCompileError, diagnostic
+	}
+	code, err := gojq.Compile(query, gojq.WithVariables(options.names))
+	if err != nil {
+		fmt.Fprintf(stderr, "jq: error: %s\njq: 1 compile error\n", err)
+		return jqExitCompileError, nil
+	}
+
+	if len(command.Files) > 0 {
+		files, err := openFiles(command.Files)
+		if err != nil {
+			fmt.Fprintf(stderr, "jq: error: %s\n", err)
+			return jqExitUsage, nil
+		}
+		defer closeFiles(files)
+		stdin = concatenate(files)
+	}
+
+	exitCode := 0
+	var last any
+	outputs := 0
+	// Helper function to run the filter on the given input, returning false if cancelled
+	run := func(input any) bool {
+		iter := code.RunWithContext(ctx, input, options.values...)
+		for {
+			value, ok := iter.Next()
+			if !ok {
+				return true
+			}
+			if err, ok := value.(error); ok {
+				if ctx.Err() != nil {
+					return false
+				}
+				fmt.Fprintf(stderr, "jq: error: %s\n", err)
+				exitCode = jqExitError
+				return true
+			}
+			if err := writeJqValue(stdout, value, options); err != nil {
+				fmt.Fprintf(stderr, "jq: error: %s\n", err)
+				exitCode = jqExitError
+				return true
+			}
+			if !options.join {
+				io.WriteString(stdout, "\n")
+			}
+			last = value
+			outputs++
+		}
+	}
+
+	if options.nullInput {
+		if !run(nil) {
+			return jqExitError, nil
+		}
+	} else {
+		// the inputs are streamed, so that the results preceding an invalid input are output, as with jq
+		decoder := json.NewDecoder(stdin)
+		decoder.UseNumber()
+		var values []any
+		for {
+			var value any
+			err := decoder.Decode(&value)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "jq: error (at offset %d): %s\n", decoder.InputOffset(), err)
+				return jqExitUsage, nil
+			}
+			if options.slurp {
+				values = append(values, value)
+			} else if !run(value) {
+				return jqExitError, nil
+			}
+		}
+		if options.slurp {
+			if values == nil {
+				values = []any{}
+			}
+			if !run(values) {
+				return jqExitError, nil
+			}
+		}
+	}
+
+	if exitCode == 0 && options.exitStatus {
+		switch {
+		case outputs == 0:
+			exitCode = jqExitNoOutput
+		case last == nil || last == false:
+			exitCode = jqExitFalsy
+		}
+	}
+	return exitCode, nil
+}
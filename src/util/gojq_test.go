@@ -0,0 +1,41 @@
+package program
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGojqEngine(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    string
+		expression string
+		stdin      string
+		stdout     string
+		exitCode   int
+	}{
+		{"values", "-c", ".a", `{"a":1} {"a":[2]}`, "1\n[2]\n", 0},
+		{"slurp", "-c -s", "map(.a)", `{"a":1} {"a":2}`, "[1,2]\n", 0},
+		{"null input", "-n", "1, 2", `invalid`, "1\n2\n", 0},
+		{"invalid input", "-c", ".a", "{\"a\":1}\n{\"a\":2}\n{invalid", "1\n2\n", jqExitUsage},
+		{"invalid slurped input", "-s", ".", "{\"a\":1}\n{invalid", "", jqExitUsage},
+		{"error", "", ".a", `{"a":1} 2 {"a":3}`, "1\n3\n", jqExitError},
+		{"exit status", "-e", ".a", `{"a":false}`, "false\n", jqExitFalsy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			var res Result
+			command := Command{Program: "jq", Options: tt.options, Expression: tt.expression}
+			gojqEngine(context.Background(), command, Settings{}, strings.NewReader(tt.stdin), &stdout, &stderr, &res)
+			if got := stdout.String(); got != tt.stdout {
+				t.Errorf("stdout = %q, want %q", got, tt.stdout)
+			}
+			if res.ExitCode != tt.exitCode {
+				t.Errorf("exit code = %d, want %d (stderr %q)", res.ExitCode, tt.exitCode, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package program
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// Prefix of the executables of plugins, which are named play-<name>
+const PluginPrefix = "play-"
+
+// Programs provided by plugins, by name
+var plugins = make(map[string]Program)
+
+// Request sent to a plugin on its standard input.
+// The action is either "run", to evaluate the expressions, or "help".
+// Stdin is the path of a file holding the standard input of the command, if any.
+type pluginRequest struct {
+	Action      string   `json:"action"`
+	Options     []string `json:"options"`
+	Expression  string   `json:"expression"`
+	Expressions []string `json:"expressions"`
+	Files       []string `json:"files"`
+	Stdin       string   `json:"stdin,omitempty"`
+}
+
+// Response of a plugin, written on its standard output.
+// The highlights are byte offsets in stdout, and the diagnostic locates the error in the expression, if any.
+type pluginResponse struct {
+	Stdout     string      `json:"stdout"`
+	Stderr     string      `json:"stderr"`
+	ExitCode   int         `json:"exit_code"`
+	Highlights []Highlight `json:"highlights"`
+	Diagnostic *struct {
+		Message string `json:"message"`
+		Offset  int    `json:"offset"`
+	} `json:"diagnostic"`
+}
+
+// Returns the plugins found on the PATH, as a map from their names to their executables.
+// Like executables, plugins found first on the PATH shadow the following ones.
+// Empty and relative entries of the PATH are skipped, so that plugins are never run from the current directory.
+func FindPlugins() map[string]string {
+	found := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok || entry.IsDir() {
+				continue
+			}
+			if _, exists := found[name]; exists {
+				continue
+			}
+			path, err := filepath.Abs(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			if _, err := exec.LookPath(path); err == nil {
+				found[name] = path
+			}
+		}
+	}
+	return found
+}
+
+// Helper function returning the name of the plugin of the given executable, if it is one.
+// On Windows the extension of the executable is left out of the name.
+func pluginName(file string) (string, bool) {
+	if !strings.HasPrefix(file, PluginPrefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(file, PluginPrefix)
+	if runtime.GOOS == "windows" {
+		extension := filepath.Ext(name)
+		for _, executable := range filepath.SplitList(os.Getenv("PATHEXT")) {
+			if strings.EqualFold(extension, executable) {
+				name = strings.TrimSuffix(name, extension)
+				break
+			}
+		}
+	}
+	return name, len(name) > 0
+}
+
+// Register the plugin of the given name, evaluated by the given executable, and return its program
+func RegisterPlugin(name string, path string) Program {
+	p := NewProgram(name, true)
+	p.Engine = PluginPrefix + name
+	p.Description = "Play with " + name + " (plugin " + path + ")"
+	backends[p.Engine] = pluginBackend{path: path}
+	plugins[name] = p
+	return p
+}
+
+// Returns the names of the programs provided by registered plugins, sorted
+func PluginNames() []string {
+	return sortedNames(plugins)
+}
+
+// Returns whether the program of the given name is provided by a registered plugin
+func IsPlugin(name string) bool {
+	_, ok := plugins[name]
+	return ok
+}
+
+// Backend evaluating commands with a plugin, speaking JSON over its standard input and output
+type pluginBackend struct {
+	path string
+}
+
+// The arguments of the command are only shown, as the plugin receives them as JSON
+func (b pluginBackend) Args(command Command) ([]string, error) {
+	return command.Args()
+}
+
+// Expressions are only validated by the plugins when evaluating them
+func (b pluginBackend) Validate(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	return nil
+}
+
+// In scratch mode the plugin operates on copies of the files of the command,
+// and the modifications made to them are returned in the result.
+func (b pluginBackend) Execute(ctx context.Context, command Command, settings Settings, stdin *string) (Result, error) {
+	options, err := SplitOptions(command.Options)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var ws *workspace
+	if settings.Scratch && len(command.Files) > 0 {
+		ws, err = newWorkspace(command.Files)
+		if err != nil {
+			return Result{}, err
+		}
+		defer ws.remove()
+		command = ws.command(command)
+	}
+
+	request := pluginRequest{
+		Action:      "run",
+		Options:     options,
+		Expression:  command.Expression,
+		Expressions: append([]string{command.Expression}, command.ExtraExpressions...),
+		Files:       command.Files,
+	}
+	visible := command.Files
+	if stdin != nil {
+		f, err := os.CreateTemp("", "play-stdin")
+		if err != nil {
+			return Result{}, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(*stdin)
+		f.Close()
+		if err != nil {
+			return Result{}, err
+		}
+		request.Stdin = f.Name()
+		visible = append(append([]string{}, visible...), f.Name())
+	}
+
+	cmd := exec.Command(b.path)
+	if len(command.Env) > 0 {
+		cmd.Env = append(os.Environ(), command.Env...)
+	}
+	if settings.Sandbox {
+		scratchDir := ""
+		if ws != nil {
+			scratchDir = ws.dir
+		}
+		if err := sandbox(cmd, visible, scratchDir); err != nil {
+			return Result{}, err
+		}
+	}
+	res, err := b.exchange(ctx, cmd, request, settings)
+	if ctx.Err() != nil {
+		return Result{}, ctx.Err()
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if ws != nil {
+		res.Changes = ws.changes()
+	}
+	return res, nil
+}
+
+// The help is returned by the plugin when asked for it
+func (b pluginBackend) Help(ctx context.Context, p Program, settings Settings) (Result, error) {
+	return b.exchange(ctx, exec.Command(b.path), pluginRequest{Action: "help"}, settings)
+}
+
+// Helper function to send the given request to the plugin and return its response as a result.
+// Invalid responses are reported as failed evaluations, along with whatever the plugin printed on stderr.
+func (b pluginBackend) exchange(ctx context.Context, cmd *exec.Cmd, request pluginRequest, settings Settings) (Result, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return Result{}, err
+	}
+	cmd.Stdin = bytes.NewReader(data)
+
+	// the response is larger than the output it holds, once encoded
+	limits := settings
+	if limits.MaxOutput > 0 {
+		limits.MaxOutput *= 4
+	}
+	res, err := execute(ctx, cmd, limits)
+	if err != nil || res.TimedOut {
+		return res, err
+	}
+
+	var response pluginResponse
+	err = json.Unmarshal([]byte(res.Stdout), &response)
+	switch {
+	case err != nil && res.Truncated:
+		// the plugin was killed once its response exceeded the limit, so only its output is too large
+		res.Stdout, res.ExitCode, res.Signal = "", 0, ""
+		res.Stderr = appendLine(res.Stderr, fmt.Sprintf("%s: response larger than %d bytes", filepath.Base(b.path), limits.MaxOutput))
+		return res, nil
+	case res.Signal != "":
+		return res, nil
+	case err != nil:
+		res.Stdout = ""
+		res.Stderr = appendLine(res.Stderr, fmt.Sprintf("%s: invalid response: %s", filepath.Base(b.path), err))
+		if res.ExitCode == 0 {
+			res.ExitCode = 1
+		}
+		return res, nil
+	}
+
+	res.Stdout, res.Stderr = response.Stdout, res.Stderr+response.Stderr
+	res.ExitCode = response.ExitCode
+	if settings.MaxOutput > 0 {
+		if len(res.Stdout) > settings.MaxOutput {
+			res.Stdout = truncateString(res.Stdout, settings.MaxOutput)
+			res.Truncated = true
+		}
+		if len(res.Stderr) > settings.MaxOutput {
+			res.Stderr = truncateString(res.Stderr, settings.MaxOutput)
+			res.Truncated = true
+		}
+	}
+	// highlights are clipped to the output, which may have been truncated, and widened to whole runes
+	for _, h := range response.Highlights {
+		if h.End > len(res.Stdout) {
+			h.End = len(res.Stdout)
+		}
+		if h.Start < 0 || h.End <= h.Start || h.Class < 0 {
+			continue
+		}
+		for !utf8.RuneStart(res.Stdout[h.Start]) {
+			h.Start--
+		}
+		for h.End < len(res.Stdout) && !utf8.RuneStart(res.Stdout[h.End]) {
+			h.End++
+		}
+		res.Highlights = append(res.Highlights, h)
+	}
+	if d := response.Diagnostic; d != nil && d.Offset >= 0 && d.Offset <= len(request.Expression) {
+		res.Diagnostic = &Diagnostic{Message: d.Message, Expression: request.Expression, Offset: d.Offset}
+	}
+	return res, nil
+}
+
+// Helper function to append the given line to the given text, on a line of its own
+func appendLine(text string, line string) string {
+	if len(text) > 0 && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text + line + "\n"
+}
+
+// Helper function to truncate the given string to at most the given number of bytes, without splitting a rune
+func truncateString(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size]
+}
+
+// The highlights of the output are the ones returned by the plugin
+func (b pluginBackend) HighlightOutput(res Result) []Highlight {
+	return res.Highlights
+}
+
+// Plugins do not highlight expressions
+func (b pluginBackend) HighlightExpression(expression string) []Highlight {
+	return nil
+}
@@ -0,0 +1,181 @@
+package program
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Helper function to create a plugin saving its request next to it and printing the given response
+func writePlugin(t *testing.T, dir string, response string) pluginBackend {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	path := filepath.Join(dir, PluginPrefix+"test")
+	script := "#!/bin/sh\ncat > '" + path + ".request'\nprintf '%s' '" + response + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return pluginBackend{path: path}
+}
+
+func TestPluginRequest(t *testing.T) {
+	dir := t.TempDir()
+	b := writePlugin(t, dir, `{"stdout": "out\n"}`)
+	command := Command{Program: "test", Options: "-x 'a b'", Expression: "foo", ExtraExpressions: []string{"bar"}, Files: []string{"a.txt"}}
+	stdin := "input"
+	if _, err := b.Execute(context.Background(), command, Settings{}, &stdin); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(b.path + ".request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request pluginRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.Stdin) == 0 {
+		t.Errorf("request has no stdin")
+	}
+	request.Stdin = ""
+	want := pluginRequest{Action: "run", Options: []string{"-x", "a b"}, Expression: "foo", Expressions: []string{"foo", "bar"}, Files: []string{"a.txt"}}
+	if !reflect.DeepEqual(request, want) {
+		t.Errorf("request = %+v, want %+v", request, want)
+	}
+}
+
+func TestPluginResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		settings Settings
+		want     Result
+	}{
+		{
+			name:     "output",
+			response: `{"stdout": "out\n", "stderr": "warning\n", "exit_code": 2}`,
+			want:     Result{Stdout: "out\n", Stderr: "warning\n", ExitCode: 2},
+		},
+		{
+			name:     "highlights",
+			response: `{"stdout": "abc", "highlights": [{"start": 0, "end": 1, "class": 0}, {"start": 2, "end": 1, "class": 0}]}`,
+			want:     Result{Stdout: "abc", Highlights: []Highlight{{Start: 0, End: 1}}},
+		},
+		{
+			name:     "highlights beyond the output",
+			response: `{"stdout": "aé", "highlights": [{"start": 2, "end": 3, "class": 0}, {"start": 1, "end": 9, "class": 1}, {"start": 3, "end": 9, "class": 0}]}`,
+			want:     Result{Stdout: "aé", Highlights: []Highlight{{Start: 1, End: 3}, {Start: 1, End: 3, Class: 1}}},
+		},
+		{
+			name:     "highlights of a truncated output",
+			response: `{"stdout": "` + strings.Repeat("a", 59) + `é", "highlights": [{"start": 58, "end": 61, "class": 0}, {"start": 59, "end": 61, "class": 0}]}`,
+			settings: Settings{MaxOutput: 60},
+			want:     Result{Stdout: strings.Repeat("a", 59), Truncated: true, Highlights: []Highlight{{Start: 58, End: 59}}},
+		},
+		{
+			name:     "diagnostic",
+			response: `{"exit_code": 1, "diagnostic": {"message": "unexpected o", "offset": 1}}`,
+			want:     Result{ExitCode: 1, Diagnostic: &Diagnostic{Message: "unexpected o", Expression: "foo", Offset: 1}},
+		},
+		{
+			name:     "diagnostic out of the expression",
+			response: `{"exit_code": 1, "diagnostic": {"message": "unexpected end", "offset": 4}}`,
+			want:     Result{ExitCode: 1},
+		},
+		{
+			name:     "invalid response",
+			response: `out`,
+			want:     Result{Stderr: "play-test: invalid response: invalid character 'o' looking for beginning of value\n", ExitCode: 1},
+		},
+		{
+			name:     "truncated output",
+			response: `{"stdout": "aaaaaaaaaé"}`,
+			settings: Settings{MaxOutput: 10},
+			want:     Result{Stdout: "aaaaaaaaa", Truncated: true},
+		},
+		{
+			name:     "response too large",
+			response: `{"stdout": "` + strings.Repeat("a", 100) + `"}`,
+			settings: Settings{MaxOutput: 10},
+			want:     Result{Stderr: "play-test: response larger than 40 bytes\n", Truncated: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := writePlugin(t, t.TempDir(), tt.response)
+			got, err := b.Execute(context.Background(), Command{Program: "test", Expression: "foo"}, tt.settings, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got.Duration, got.MaxRSS = 0, 0
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Execute() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	for _, dir := range []string{first, second} {
+		writePlugin(t, dir, "{}")
+	}
+	if err := os.WriteFile(filepath.Join(first, PluginPrefix+"data"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(second); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want map[string]string
+	}{
+		{first + string(os.PathListSeparator) + second, map[string]string{"test": filepath.Join(first, PluginPrefix+"test")}},
+		{second + string(os.PathListSeparator) + first, map[string]string{"test": filepath.Join(second, PluginPrefix+"test")}},
+		{"." + string(os.PathListSeparator) + string(os.PathListSeparator), map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Setenv("PATH", tt.path)
+			if got := FindPlugins(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindPlugins() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		s    string
+		size int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 2, "ab"},
+		{"aé", 2, "a"},
+		{"aé", 3, "aé"},
+		{"日本", 4, "日"},
+		{"é", 1, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateString(tt.s, tt.size); got != tt.want {
+			t.Errorf("truncateString(%q, %d) = %q, want %q", tt.s, tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package program
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// Run the command in its own process group so that it can be killed along with its children
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// Kill the process tree of the given command
+func killProcessGroup(cmd *exec.Cmd) error {
+	err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	if err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// Returns the name of the signal which terminated the process, if any
+func signalName(state *os.ProcessState) string {
+	return ""
+}
+
+// Returns the peak resident set size of the process in bytes, or 0 if unknown
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
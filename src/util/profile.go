@@ -0,0 +1,162 @@
+package program
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Programs defined by profiles, by name
+var profiles = make(map[string]Program)
+
+// Profile of a program, as defined in a YAML file of the profiles directory
+type profile struct {
+	Name         string   `yaml:"name"`
+	Description  string   `yaml:"description"`
+	Path         string   `yaml:"path"`
+	Options      string   `yaml:"options"`
+	Template     string   `yaml:"template"`
+	Quote        string   `yaml:"quote"`
+	Files        string   `yaml:"files"`
+	Extensions   []string `yaml:"extensions"`
+	Help         string   `yaml:"help"`
+	EndOfOptions *bool    `yaml:"end_of_options"`
+}
+
+// Returns the directory holding the profiles of programs,
+// $XDG_CONFIG_HOME/play/programs or ~/.config/play/programs by default
+func ProfilesDir() (string, error) {
+	config := os.Getenv("XDG_CONFIG_HOME")
+	if len(config) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		config = filepath.Join(home, ".config")
+	}
+	return filepath.Join(config, "play", "programs"), nil
+}
+
+// Register the program defined by a profile, so that it can be looked up by its name
+func RegisterProfile(p Program) {
+	profiles[p.Name] = p
+}
+
+// Returns the names of the programs defined by registered profiles, sorted
+func ProfileNames() []string {
+	return sortedNames(profiles)
+}
+
+// Helper function returning the names of the given programs, sorted
+func sortedNames(programs map[string]Program) []string {
+	names := make([]string, 0, len(programs))
+	for name := range programs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load the profiles of programs defined in the .yaml and .yml files of the given directory.
+// Invalid profiles are skipped, and the reason why is returned along with the valid ones.
+func LoadProfiles(dir string) ([]Program, []error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var names []string
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		if !entry.IsDir() && (extension == ".yaml" || extension == ".yml") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var programs []Program
+	var errs []error
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		p, err := loadProfile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid profile %s: %w", path, err))
+			continue
+		}
+		programs = append(programs, p)
+	}
+	return programs, errs
+}
+
+// Helper function to load the profile defined in the given file
+func loadProfile(path string) (Program, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Program{}, err
+	}
+	var pr profile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&pr); err != nil {
+		return Program{}, err
+	}
+
+	if len(pr.Name) == 0 || strings.ContainsAny(pr.Name, " \t\n/") || strings.HasPrefix(pr.Name, "-") {
+		return Program{}, fmt.Errorf("invalid name '%s'", pr.Name)
+	}
+	p := NewProgram(pr.Name, pr.EndOfOptions == nil || *pr.EndOfOptions)
+	p.Description = pr.Description
+	p.Options = pr.Options
+
+	if len(pr.Path) > 0 {
+		words, err := SplitOptions(pr.Path)
+		if err != nil || len(words) == 0 {
+			return Program{}, fmt.Errorf("invalid path '%s'", pr.Path)
+		}
+		p.Path = words[0]
+		p.PathArgs = words[1:]
+	}
+
+	if len(pr.Template) > 0 {
+		if _, err := ParseTemplate(pr.Template); err != nil {
+			return Program{}, fmt.Errorf("invalid template '%s': %w", pr.Template, err)
+		}
+		p.Template = pr.Template
+	}
+
+	if len(pr.Quote) > 0 {
+		quote, ok := QuoteStyles[pr.Quote]
+		if !ok {
+			return Program{}, fmt.Errorf("invalid quote '%s', valid quotes are single, double and none", pr.Quote)
+		}
+		p.Quote = quote
+	}
+
+	switch pr.Files {
+	case "":
+	case FilesAsArguments, FilesOnStdin:
+		p.FilePlacement = pr.Files
+	default:
+		return Program{}, fmt.Errorf("invalid files '%s', valid values are %s and %s", pr.Files, FilesAsArguments, FilesOnStdin)
+	}
+
+	for _, extension := range pr.Extensions {
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		p.Extensions = append(p.Extensions, extension)
+	}
+
+	if len(pr.Help) > 0 {
+		p.HelpFlag = pr.Help
+	}
+	return p, nil
+}
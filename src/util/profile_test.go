@@ -0,0 +1,84 @@
+package program
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    Program
+		wantErr bool
+	}{
+		{name: "defaults", yaml: "name: a\n", want: NewProgram("a", true)},
+		{
+			name: "complete",
+			yaml: "name: up\ndescription: Uppercase\npath: busybox tr\noptions: -d\ntemplate: '{options} {expr1} {expr2}'\nquote: double\nfiles: stdin\nextensions: [txt, .log]\nhelp: -h\nend_of_options: false\n",
+			want: Program{
+				Name: "up", Description: "Uppercase", Path: "busybox", PathArgs: []string{"tr"}, Options: "-d",
+				Template: "{options} {expr1} {expr2}", Quote: `"`, FilePlacement: FilesOnStdin,
+				Extensions: []string{".txt", ".log"}, HelpFlag: "-h",
+			},
+		},
+		{name: "missing name", yaml: "description: nameless\n", wantErr: true},
+		{name: "name with a space", yaml: "name: a b\n", wantErr: true},
+		{name: "name with a slash", yaml: "name: a/b\n", wantErr: true},
+		{name: "name starting with a dash", yaml: "name: -a\n", wantErr: true},
+		{name: "unknown field", yaml: "name: a\ncolor: red\n", wantErr: true},
+		{name: "unterminated path", yaml: "name: a\npath: \"'busybox\"\n", wantErr: true},
+		{name: "invalid template", yaml: "name: a\ntemplate: '{expr2}'\n", wantErr: true},
+		{name: "invalid quote", yaml: "name: a\nquote: backtick\n", wantErr: true},
+		{name: "invalid files", yaml: "name: a\nfiles: pipe\n", wantErr: true},
+		{name: "invalid yaml", yaml: "name: [a\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "profile.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadProfile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadProfile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.yml":      "name: b\n",
+		"a.yaml":     "name: a\n",
+		"bad.yaml":   "name: a b\n",
+		"notes.txt":  "name: c\n",
+		"dir.yaml/x": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	programs, errs := LoadProfiles(dir)
+	var names []string
+	for _, p := range programs {
+		names = append(names, p.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) || len(errs) != 1 {
+		t.Errorf("LoadProfiles() = %v and %v, want [a b] and the error of bad.yaml", names, errs)
+	}
+	if programs, errs := LoadProfiles(filepath.Join(dir, "missing")); programs != nil || errs != nil {
+		t.Errorf("LoadProfiles() of a missing directory = %v, %v", programs, errs)
+	}
+}
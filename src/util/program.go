@@ -2,15 +2,63 @@ package program
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Program being used
 type Program struct {
 	Name                 string
+	Description          string
 	RespectsEndOfOptions bool
+	// implementation of the program, such as "mawk" or "busybox awk", looked up by name when empty
+	Path     string
+	PathArgs []string
+	// how the input files are passed, FilesAsArguments or FilesOnStdin
+	FilePlacement string
+	// quote initially used around the positional arguments
+	Quote   string
+	Options string
+	// argument template, see Command
+	Template string
+	// extensions of the accepted files, any when empty
+	Extensions []string
+	HelpFlag   string
+	// builtin engine evaluating the program in place of its executable, if any
+	Engine string
+}
+
+// Ways of passing the input files to a program
+const (
+	FilesAsArguments = "arguments"
+	FilesOnStdin     = "stdin"
+)
+
+// Quotes which can be used around the positional arguments, by name
+var QuoteStyles = map[string]string{
+	"single": "'",
+	"double": `"`,
+	"none":   "",
+}
+
+// Settings applied to each evaluation, and number of runs of benchmarks
+type Settings struct {
+	Timeout         time.Duration
+	MaxOutput       int
+	Shell           bool
+	Sandbox         bool
+	Scratch         bool
+	Cache           *Cache
+	BenchmarkRuns   int
+	BenchmarkWarmup int
 }
 
 // Program constructor
@@ -18,33 +66,301 @@ func NewProgram(name string, respectsEndOfOptions bool) Program {
 	program := Program{
 		Name:                 name,
 		RespectsEndOfOptions: respectsEndOfOptions,
+		FilePlacement:        FilesAsArguments,
+		Quote:                "'",
+		HelpFlag:             "--help",
 	}
 	return program
 }
 
-// Execute the given command in either bash or powershell depending on the detected os
-func shellout(command string, silent bool) (string, string, error) {
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd := &exec.Cmd{}
+// Returns the program using the given implementation, such as "gawk" or "busybox awk"
+func (p Program) WithImplementation(implementation string) (Program, error) {
+	words, err := SplitOptions(implementation)
+	if err != nil {
+		return p, err
+	}
+	if len(words) == 0 {
+		return p, errors.New("empty implementation")
+	}
+	if _, err := exec.LookPath(words[0]); err != nil {
+		return p, fmt.Errorf("%s not found", words[0])
+	}
+	p.Path = words[0]
+	p.PathArgs = words[1:]
+	p.Engine = ""
+	return p, nil
+}
+
+// Returns the executable of the program
+func (p Program) Executable() string {
+	if len(p.Path) == 0 {
+		return p.Name
+	}
+	return p.Path
+}
+
+// Returns the name under which the program is shown, including its implementation
+func (p Program) DisplayName() string {
+	if len(p.Engine) > 0 {
+		return p.Name + " (" + p.Engine + ")"
+	}
+	if len(p.Path) == 0 {
+		return p.Name
+	}
+	return strings.Join(append([]string{p.Path}, p.PathArgs...), " ")
+}
+
+// Programs with a dedicated command
+var Builtins = map[string]Program{
+	"grep":  NewProgram("grep", true),
+	"sed":   NewProgram("sed", true),
+	"awk":   NewProgram("awk", true),
+	"jq":    NewProgram("jq", false),
+	"yq":    NewProgram("yq", false),
+	"regex": NewProgram("regex", true),
+}
+
+// Returns the program with the given name.
+// Programs without a dedicated command are assumed to respect the end of options separator.
+func Lookup(name string) Program {
+	if program, exists := Builtins[name]; exists {
+		return program
+	}
+	if program, exists := profiles[name]; exists {
+		return program
+	}
+	if program, exists := plugins[name]; exists {
+		return program
+	}
+	return NewProgram(name, true)
+}
+
+// Buffer which stops capturing once its limit is reached.
+// The buffer is deliberately not embedded so that io.Copy cannot bypass Write.
+type cappedBuffer struct {
+	buf        bytes.Buffer
+	limit      int
+	truncated  bool
+	onTruncate func()
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.buf.Write(p)
+	}
+	if remaining := b.limit - b.buf.Len(); len(p) > remaining {
+		b.buf.Write(p[:remaining])
+		if !b.truncated {
+			b.truncated = true
+			b.onTruncate()
+		}
+		// pretend the write succeeded, the process is being killed anyway
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// Helper function to open the given files, closing them all if any fails to open
+func openFiles(paths []string) ([]*os.File, error) {
+	var files []*os.File
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			closeFiles(files)
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Helper function to close the given files
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// Returns a reader concatenating the given files
+func concatenate(files []*os.File) io.Reader {
+	readers := make([]io.Reader, len(files))
+	for i, f := range files {
+		readers[i] = f
+	}
+	return io.MultiReader(readers...)
+}
+
+// Returns the shell executing the commands in shell mode, followed by its option taking a script:
+// either bash or powershell depending on the detected os
+func Shell() []string {
 	if runtime.GOOS == "windows" {
-		cmd = exec.Command("powershell", "-command", command)
+		return []string{"powershell", "-command"}
+	}
+	return []string{"bash", "-c"}
+}
+
+// Returns the process for the given command.
+// In shell mode the command is executed in the shell, otherwise the program is executed directly.
+func newProcess(command Command, settings Settings, scratchDir string) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	if settings.Shell {
+		shell := Shell()
+		cmd = exec.Command(shell[0], shell[1], command.script())
 	} else {
-		cmd = exec.Command("bash", "-c", command)
+		args, err := externalBackend{}.Args(command)
+		if err != nil {
+			return nil, err
+		}
+		cmd = exec.Command(args[0], args[1:]...)
+	}
+	if len(command.Env) > 0 {
+		cmd.Env = append(os.Environ(), command.Env...)
 	}
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	return stdout.String(), stderr.String(), err
+
+	if settings.Sandbox {
+		if err := sandbox(cmd, command.Files, scratchDir); err != nil {
+			return nil, err
+		}
+	}
+	return cmd, nil
 }
 
-func Run(command string) (res string, err error) {
+// Execute the given process.
+// The whole process group is killed when the context is done, when the timeout
+// expires or when the output exceeds the maximum size.
+func execute(ctx context.Context, cmd *exec.Cmd, settings Settings) (Result, error) {
+	timeoutCtx := ctx
+	if settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		timeoutCtx, cancel = context.WithTimeout(ctx, settings.Timeout)
+		defer cancel()
+	}
+	killCtx, kill := context.WithCancel(timeoutCtx)
+	defer kill()
+
+	stdout := &cappedBuffer{limit: settings.MaxOutput, onTruncate: kill}
+	stderr := &cappedBuffer{limit: settings.MaxOutput, onTruncate: kill}
+	setProcessGroup(cmd)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return Result{}, err
+	}
 
-	stdout, stderr, err := shellout(command, true)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-killCtx.Done():
+			_ = killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+
+	res := Result{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  cmd.ProcessState.ExitCode(),
+		Signal:    signalName(cmd.ProcessState),
+		Duration:  time.Since(start),
+		Truncated: stdout.truncated || stderr.truncated,
+		TimedOut:  ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded,
+		MaxRSS:    maxRSS(cmd.ProcessState),
+	}
+	// a process killed once its output was truncated did not fail by itself
+	if res.Truncated && killCtx.Err() != nil && timeoutCtx.Err() == nil {
+		res.ExitCode, res.Signal = 0, ""
+	}
+	if _, ok := err.(*exec.ExitError); !ok && err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// Returns the help of the given program, as printed with its help flag or by its builtin engine
+func Help(ctx context.Context, p Program, settings Settings) (Result, error) {
+	b := p.Backend()
+	if b == nil {
+		return Result{}, fmt.Errorf("unknown engine %s", p.Engine)
+	}
+	return b.Help(ctx, p, settings)
+}
+
+// Run the given command with its backend.
+// In scratch mode the command operates on copies of its files, and the
+// modifications made to them are returned in the result.
+// An error is returned if the context is done before the command completes
+// or if the command could not be started at all.
+func Run(ctx context.Context, command Command, settings Settings) (Result, error) {
+	return run(ctx, command, settings, nil)
+}
+
+// Run the given commands as a pipeline, feeding the output of each command to the next one.
+// The result of every command is returned so that intermediate outputs can be inspected.
+func RunPipeline(ctx context.Context, commands []Command, settings Settings) ([]Result, error) {
+	var results []Result
+	var stdin *string
+	for _, command := range commands {
+		res, err := run(ctx, command, settings, stdin)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+		stdin = &results[len(results)-1].Stdout
+	}
+	return results, nil
+}
+
+// Run the given pipelines concurrently, returning the results and the error of each one
+func RunPipelines(ctx context.Context, pipelines [][]Command, settings Settings) ([][]Result, []error) {
+	results := make([][]Result, len(pipelines))
+	errs := make([]error, len(pipelines))
+	var wg sync.WaitGroup
+	for i, commands := range pipelines {
+		wg.Add(1)
+		go func(i int, commands []Command) {
+			defer wg.Done()
+			results[i], errs[i] = RunPipeline(ctx, commands, settings)
+		}(i, commands)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// Run the given command, reading its standard input from stdin if not nil.
+// Results are looked up in and stored to the cache, if any.
+func run(ctx context.Context, command Command, settings Settings, stdin *string) (Result, error) {
+
+	key := ""
+	if settings.Cache != nil {
+		// commands whose key cannot be computed are simply not cached
+		key, _ = settings.Cache.key(command, settings, stdin)
+		if len(key) > 0 {
+			if res, ok := settings.Cache.get(key); ok {
+				res.Cached = true
+				return res, nil
+			}
+		}
+	}
+
+	b := command.Backend()
+	if b == nil {
+		return Result{}, fmt.Errorf("unknown engine %s", command.Engine)
+	}
+	res, err := b.Execute(ctx, command, settings, stdin)
 	if err != nil {
-		stderr = stderr + fmt.Sprint(err)
-		return stderr, nil
+		return Result{}, err
+	}
+	// timeouts depend on the load of the machine, so they are not worth remembering
+	if len(key) > 0 && !res.TimedOut {
+		settings.Cache.put(key, res)
 	}
 
-	return stdout, nil
+	return res, nil
 }
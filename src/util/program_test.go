@@ -0,0 +1,64 @@
+package program
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestExecuteTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command is a shell script")
+	}
+	// the grandchild holds the output open, so the command only completes once the whole process group is killed
+	cmd := exec.Command("sh", "-c", "sleep 10 & wait")
+	start := time.Now()
+	res, err := execute(context.Background(), cmd, Settings{Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("execute() took %s", elapsed)
+	}
+	if !res.TimedOut || res.Success() {
+		t.Errorf("execute() = %+v, want a timed out result", res)
+	}
+}
+
+func TestExecuteCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command is a shell script")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res, err := execute(ctx, exec.Command("sh", "-c", "sleep 10 & wait"), Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("execute() took %s", elapsed)
+	}
+	// a cancelled evaluation is superseded rather than timed out
+	if res.TimedOut {
+		t.Errorf("execute() = %+v, want a result which did not time out", res)
+	}
+}
+
+func TestExecuteMaxOutput(t *testing.T) {
+	if _, err := exec.LookPath("yes"); err != nil {
+		t.Skip("yes not found")
+	}
+	res, err := execute(context.Background(), exec.Command("yes"), Settings{Timeout: 10 * time.Second, MaxOutput: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Truncated || res.TimedOut || len(res.Stdout) != 1000 {
+		t.Errorf("execute() = %d bytes, truncated %v and timed out %v, want 1000 truncated bytes", len(res.Stdout), res.Truncated, res.TimedOut)
+	}
+	if status := res.Status(); status != "truncated" || !res.Success() {
+		t.Errorf("Status() = %q, want a successful \"truncated\"", status)
+	}
+}
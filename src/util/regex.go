@@ -0,0 +1,303 @@
+package program
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// Exit statuses of the regexp engine, the same as those of grep
+const (
+	regexExitNoMatch = 1
+	regexExitError   = 2
+)
+
+// Maximum number of matches reported by the regexp engine
+const maxMatches = 10000
+
+// Help of the regexp engine
+const regexHelp = `Usage: regex [OPTIONS] PATTERN [FILES...]
+
+Go regular expressions (RE2 syntax) evaluated by the builtin regexp engine.
+Every match and capture group of the pattern is highlighted in the input.
+
+Supported options:
+  -i   case-insensitive matching
+  -m   multi-line mode: ^ and $ match at line boundaries
+  -s   let . match newlines
+  -U   ungreedy: swap the meaning of x* and x*?, x+ and x+?, etc.
+  -o   output only the matches, one per line
+  -l   leftmost-longest matching, as in POSIX
+
+The exit status is 0 if there is a match, 1 if there is none and 2 on errors.
+`
+
+// Names of the options of the regexp engine
+var regexOptionNames = []string{"-i", "-m", "-s", "-U", "-o", "-l"}
+
+// Options of the regexp engine
+type regexOptions struct {
+	flags   string
+	only    bool
+	longest bool
+}
+
+// Helper function to parse the options of the regexp engine
+func parseRegexOptions(words []string) (regexOptions, error) {
+	var options regexOptions
+	for _, word := range words {
+		if !strings.HasPrefix(word, "-") || strings.HasPrefix(word, "--") || len(word) == 1 {
+			return options, fmt.Errorf("unsupported option %s", word)
+		}
+		// short options can be combined, as in -im
+		for _, letter := range word[1:] {
+			switch letter {
+			case 'i', 'm', 's', 'U':
+				if !strings.ContainsRune(options.flags, letter) {
+					options.flags += string(letter)
+				}
+			case 'o':
+				options.only = true
+			case 'l':
+				options.longest = true
+			default:
+				return options, fmt.Errorf("unsupported option -%c", letter)
+			}
+		}
+	}
+	return options, nil
+}
+
+// Returns the diagnostic of the given error of the compilation of a regular expression
+func regexDiagnostic(pattern string, err error) *Diagnostic {
+	offset := 0
+	message := err.Error()
+	var syntaxError *syntax.Error
+	if errors.As(err, &syntaxError) {
+		message = string(syntaxError.Code)
+		switch syntaxError.Code {
+		case syntax.ErrMissingParen:
+			// the expression of the error is the whole pattern
+			offset = len(pattern)
+		case syntax.ErrUnexpectedParen:
+			offset = strings.LastIndex(pattern, ")")
+		default:
+			offset = strings.Index(pattern, syntaxError.Expr)
+		}
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return &Diagnostic{Message: message, Expression: pattern, Offset: offset}
+}
+
+// Helper function to compile the given pattern with the given options
+func compileRegex(pattern string, options regexOptions) (*regexp.Regexp, error) {
+	if len(options.flags) > 0 {
+		pattern = "(?" + options.flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if options.longest {
+		re.Longest()
+	}
+	return re, nil
+}
+
+// Returns the error in the pattern of the given command, if any
+func validateRegex(command Command) *Diagnostic {
+	var options regexOptions
+	if words, err := SplitOptions(command.Options); err == nil {
+		// the pattern is validated even if the options are not
+		options, _ = parseRegexOptions(words)
+	}
+	if _, err := compileRegex(command.Expression, options); err != nil {
+		return regexDiagnostic(command.Expression, err)
+	}
+	return nil
+}
+
+// Returns the matches of the given regular expression in the given text, at most n of them
+func findMatches(re *regexp.Regexp, text string, n int) []Match {
+	names := re.SubexpNames()
+	var matches []Match
+	for _, indices := range re.FindAllStringSubmatchIndex(text, n) {
+		match := Match{Start: indices[0], End: indices[1]}
+		for i := 1; i < len(names); i++ {
+			match.Groups = append(match.Groups, Group{
+				Index: i,
+				Name:  names[i],
+				Start: indices[2*i],
+				End:   indices[2*i+1],
+			})
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// Input of the regexp engine: the contents of a file, or of the standard input if the file is empty
+type regexInput struct {
+	file string
+	text string
+}
+
+// Helper function to read the given files, or the standard input if there are none
+func readInputs(files []string, stdin io.Reader) ([]regexInput, error) {
+	if len(files) == 0 {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		return []regexInput{{text: string(data)}}, nil
+	}
+	inputs := make([]regexInput, len(files))
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = regexInput{file: file, text: string(data)}
+	}
+	return inputs, nil
+}
+
+// Engine evaluating Go regular expressions.
+// The output is the input, or only the matches with -o. The matches are reported at their offsets in the output,
+// along with their file and the shift between their offsets in the output and in their file.
+func regexpEngine(ctx context.Context, command Command, settings Settings, stdin io.Reader, stdout io.Writer, stderr io.Writer, res *Result) {
+	words, err := SplitOptions(command.Options)
+	var options regexOptions
+	if err == nil {
+		options, err = parseRegexOptions(words)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "regex: %s\n", err)
+		res.ExitCode = regexExitError
+		return
+	}
+
+	re, err := compileRegex(command.Expression, options)
+	if err != nil {
+		res.Diagnostic = regexDiagnostic(command.Expression, err)
+		fmt.Fprintf(stderr, "regex: %s\n", err)
+		res.ExitCode = regexExitError
+		return
+	}
+
+	// each file is searched on its own, so that the matches are reported at their offsets in their file
+	inputs, err := readInputs(command.Files, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "regex: %s\n", err)
+		res.ExitCode = regexExitError
+		return
+	}
+	var sb strings.Builder
+	var matches []Match
+	truncated := false
+	for _, input := range inputs {
+		text := input.text
+		found := findMatches(re, text, maxMatches+1-len(matches))
+		if len(matches)+len(found) > maxMatches {
+			found = found[:maxMatches-len(matches)]
+			truncated = true
+		}
+		if options.only {
+			// the matches are moved to their offsets in the output
+			for i := range found {
+				match := &found[i]
+				start := sb.Len()
+				sb.WriteString(text[match.Start:match.End])
+				sb.WriteString("\n")
+				match.move(start - match.Start)
+			}
+		} else {
+			for i := range found {
+				found[i].move(sb.Len())
+			}
+			sb.WriteString(text)
+		}
+		for i := range found {
+			found[i].File = input.file
+		}
+		matches = append(matches, found...)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+	if truncated {
+		fmt.Fprintf(stderr, "regex: only the first %d matches are reported\n", maxMatches)
+	}
+	io.WriteString(stdout, sb.String())
+
+	res.Matches = matches
+	if len(matches) == 0 {
+		res.ExitCode = regexExitNoMatch
+	}
+}
+
+// Backend of the regexp engine, highlighting the capture groups of the patterns and the matches in the outputs
+type regexBackend struct {
+	*engineBackend
+}
+
+// The class of each capture group is its index
+func (regexBackend) HighlightExpression(expression string) []Highlight {
+	var highlights []Highlight
+	var open []int
+	group := 0
+	inClass := false
+	for i := 0; i < len(expression); i++ {
+		switch c := expression[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+			// a closing bracket right after the opening one is part of the class
+			if strings.HasPrefix(expression[i+1:], "^]") {
+				i += 2
+			} else if strings.HasPrefix(expression[i+1:], "]") {
+				i++
+			}
+		case c == '(':
+			rest := expression[i+1:]
+			capturing := !strings.HasPrefix(rest, "?") || strings.HasPrefix(rest, "?P<") || strings.HasPrefix(rest, "?<")
+			if capturing {
+				group++
+				highlights = append(highlights, Highlight{Start: i, End: len(expression), Class: group})
+				open = append(open, len(highlights)-1)
+			} else {
+				open = append(open, -1)
+			}
+		case c == ')' && len(open) > 0:
+			if h := open[len(open)-1]; h >= 0 {
+				highlights[h].End = i + 1
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	return highlights
+}
+
+// The class of each match is 0, and the class of each capture group its index
+func (regexBackend) HighlightOutput(res Result) []Highlight {
+	var highlights []Highlight
+	for _, match := range res.Matches {
+		highlights = append(highlights, Highlight{Start: match.Start, End: match.End})
+		for _, group := range match.Groups {
+			if group.Start >= 0 {
+				highlights = append(highlights, Highlight{Start: group.Start, End: group.End, Class: group.Index})
+			}
+		}
+	}
+	return highlights
+}
@@ -0,0 +1,65 @@
+package program
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegexMatchOffsets(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.txt"), filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("apple\nbanana\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("cherry\nan\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	type offsets struct {
+		file       string
+		start, end int
+	}
+	tests := []struct {
+		name    string
+		command Command
+		stdin   string
+		want    []offsets
+	}{
+		{"stdin", Command{Expression: "an"}, "banana\n", []offsets{{"", 1, 3}, {"", 3, 5}}},
+		{"files", Command{Expression: "a(n)", Files: []string{first, second}}, "", []offsets{{first, 7, 9}, {first, 9, 11}, {second, 7, 9}}},
+		{"only", Command{Options: "-o", Expression: "a(n)", Files: []string{first, second}}, "", []offsets{{first, 7, 9}, {first, 9, 11}, {second, 7, 9}}},
+		{"no match across files", Command{Expression: `a\ncherry`, Files: []string{first, second}}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			var res Result
+			regexpEngine(context.Background(), tt.command, Settings{}, strings.NewReader(tt.stdin), &stdout, &stderr, &res)
+			if stderr.Len() > 0 {
+				t.Fatal(stderr.String())
+			}
+			if len(res.Matches) != len(tt.want) {
+				t.Fatalf("got %d matches, want %d", len(res.Matches), len(tt.want))
+			}
+			output := stdout.String()
+			for i, match := range res.Matches {
+				got := offsets{match.File, match.Start - match.Shift, match.End - match.Shift}
+				if got != tt.want[i] {
+					t.Errorf("match %d is at %+v, want %+v", i, got, tt.want[i])
+				}
+				if text := output[match.Start:match.End]; text != "an" {
+					t.Errorf("match %d is %q in the output, want \"an\"", i, text)
+				}
+				for _, group := range match.Groups {
+					if output[group.Start:group.End] != "n" {
+						t.Errorf("group of match %d is %q in the output, want \"n\"", i, output[group.Start:group.End])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package program
+
+import (
+	"strconv"
+	"time"
+)
+
+// Result of an evaluation
+type Result struct {
+	Stdout     string
+	Stderr     string
+	ExitCode   int
+	Signal     string
+	Duration   time.Duration
+	Truncated  bool
+	TimedOut   bool
+	Changes    []Change
+	Cached     bool
+	MaxRSS     int64
+	Diagnostic *Diagnostic
+	Variables  []Variable
+	Matches    []Match
+	Highlights []Highlight
+}
+
+// Variable of a program, or element of an array variable, and its value
+type Variable struct {
+	Name  string
+	Value string
+}
+
+// Match of a regular expression, at the given byte offsets of the output.
+// The match was found in the given file, or in the standard input if File is empty,
+// where its offsets are those of the output minus Shift.
+type Match struct {
+	Start  int
+	End    int
+	Groups []Group
+	File   string
+	Shift  int
+}
+
+// Helper function to move the match and its groups by the given number of bytes in the output
+func (m *Match) move(n int) {
+	m.Start += n
+	m.End += n
+	m.Shift += n
+	for i, group := range m.Groups {
+		if group.Start >= 0 {
+			m.Groups[i].Start += n
+			m.Groups[i].End += n
+		}
+	}
+}
+
+// Capture group of a match, at the given byte offsets of the output.
+// The offsets are -1 when the group is not part of the match.
+type Group struct {
+	Index int
+	Name  string
+	Start int
+	End   int
+}
+
+// Error in the expression of a command, at the given byte offset of the expression
+type Diagnostic struct {
+	Message    string
+	Expression string
+	Offset     int
+}
+
+// Returns whether the program exited successfully
+func (r Result) Success() bool {
+	return r.ExitCode == 0 && r.Signal == "" && !r.TimedOut
+}
+
+// Returns a short description of how the program terminated
+func (r Result) Status() string {
+	switch {
+	case r.TimedOut:
+		return "timed out"
+	case r.Signal != "":
+		return "signal " + r.Signal
+	case r.Truncated && r.ExitCode == 0:
+		return "truncated"
+	default:
+		return "exit " + strconv.Itoa(r.ExitCode)
+	}
+}
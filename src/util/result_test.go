@@ -0,0 +1,23 @@
+package program
+
+import "testing"
+
+func TestResultStatus(t *testing.T) {
+	tests := []struct {
+		result  Result
+		status  string
+		success bool
+	}{
+		{Result{}, "exit 0", true},
+		{Result{ExitCode: 2}, "exit 2", false},
+		{Result{ExitCode: -1, Signal: "killed"}, "signal killed", false},
+		{Result{TimedOut: true, ExitCode: -1, Signal: "killed"}, "timed out", false},
+		{Result{Truncated: true}, "truncated", true},
+		{Result{Truncated: true, ExitCode: 1}, "exit 1", false},
+	}
+	for _, tt := range tests {
+		if status := tt.result.Status(); status != tt.status || tt.result.Success() != tt.success {
+			t.Errorf("Status() = %q and Success() = %v for %+v, want %q and %v", status, tt.result.Success(), tt.result, tt.status, tt.success)
+		}
+	}
+}
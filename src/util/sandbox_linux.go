@@ -0,0 +1,235 @@
+//go:build linux
+
+package program
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Argument used to re-execute play as the sandbox helper
+const SandboxCommand = "__sandbox"
+
+// Returns whether the sandbox is supported on this platform
+func SandboxSupported() bool {
+	return true
+}
+
+// Wrap the given process so that it runs inside the sandbox.
+// The process is started through play itself in new user, mount and network
+// namespaces; the helper then sets up the mounts and executes the program.
+// The given files are kept visible even if they live in the temp directory,
+// while the writable directory, if any, is the only place which can be modified.
+func sandbox(cmd *exec.Cmd, files []string, writableDir string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{self, SandboxCommand}
+	if len(writableDir) > 0 {
+		args = append(args, "-w", writableDir)
+	}
+	for _, file := range files {
+		if abs, err := filepath.Abs(file); err == nil {
+			args = append(args, abs)
+		}
+	}
+	args = append(args, "--")
+	args = append(args, cmd.Args...)
+
+	cmd.Path = self
+	cmd.Args = args
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET,
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1},
+		},
+		GidMappingsEnableSetgroups: false,
+	}
+	return nil
+}
+
+// Helper function to tell whether path is inside dir
+func isInside(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Returns the mount flags which must be preserved when remounting path
+func lockedMountFlags(path string) uintptr {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0
+	}
+	// statvfs flags, see statvfs(3)
+	flags := map[int64]uintptr{
+		0x0002: syscall.MS_NOSUID,
+		0x0004: syscall.MS_NODEV,
+		0x0008: syscall.MS_NOEXEC,
+		0x0400: syscall.MS_NOATIME,
+		0x0800: syscall.MS_NODIRATIME,
+		0x1000: syscall.MS_RELATIME,
+	}
+	var locked uintptr
+	for st, ms := range flags {
+		if stat.Flags&st != 0 {
+			locked |= ms
+		}
+	}
+	return locked
+}
+
+// Bind mount source onto target
+func bind(source string, target string) error {
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", target, err)
+	}
+	return nil
+}
+
+// Bind mount source onto target and make it read-only
+func bindReadOnly(source string, target string) error {
+	if err := bind(source, target); err != nil {
+		return err
+	}
+	flags := syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | lockedMountFlags(target)
+	if err := syscall.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("remount %s read-only: %w", target, err)
+	}
+	return nil
+}
+
+// Set up the sandbox mounts, keeping the working directory and the given files visible
+// and the writable directory writable
+func setupSandbox(workDir string, files []string, writableDir string) error {
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+
+	// keep a handle on everything which is about to be hidden by the private /tmp
+	tmpDir := filepath.Clean(os.TempDir())
+	hidden := map[string]*os.File{}
+	paths := append([]string{workDir}, files...)
+	if len(writableDir) > 0 {
+		paths = append(paths, writableDir)
+	}
+	for _, path := range paths {
+		if !isInside(path, tmpDir) {
+			continue
+		}
+		// files in the writable directory come along with it
+		if len(writableDir) > 0 && path != writableDir && isInside(path, writableDir) {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		hidden[path] = f
+	}
+
+	if err := syscall.Mount("tmpfs", tmpDir, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mount private %s: %w", tmpDir, err)
+	}
+
+	for path, f := range hidden {
+		stat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if stat.IsDir() {
+			err = os.MkdirAll(path, 0755)
+		} else {
+			err = os.MkdirAll(filepath.Dir(path), 0755)
+			if err == nil {
+				err = os.WriteFile(path, nil, 0644)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		source := fmt.Sprintf("/proc/self/fd/%d", f.Fd())
+		if path == writableDir {
+			err = bind(source, path)
+		} else {
+			err = bindReadOnly(source, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, ok := hidden[writableDir]; !ok && len(writableDir) > 0 && isInside(writableDir, workDir) {
+		if err := bind(writableDir, writableDir); err != nil {
+			return err
+		}
+	}
+	if _, ok := hidden[workDir]; !ok {
+		if err := bindReadOnly(workDir, workDir); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		if len(writableDir) > 0 && isInside(file, writableDir) {
+			continue
+		}
+		if _, ok := hidden[file]; !ok && !isInside(file, workDir) {
+			if err := bindReadOnly(file, file); err != nil {
+				return err
+			}
+		}
+	}
+
+	// re-enter the working directory so that the new mount is used
+	return os.Chdir(workDir)
+}
+
+// Entry point of the sandbox helper.
+// The arguments are an optional writable directory preceded by "-w", the files
+// to keep visible, followed by "--" and the program to execute along with its
+// arguments.
+func SandboxMain(args []string) {
+	writableDir := ""
+	if len(args) > 1 && args[0] == "-w" {
+		writableDir = args[1]
+		args = args[2:]
+	}
+	var files []string
+	for len(args) > 0 && args[0] != "--" {
+		files = append(files, args[0])
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, "sandbox: missing program")
+		os.Exit(126)
+	}
+	args = args[1:]
+
+	fail := func(err error) {
+		fmt.Fprintln(os.Stderr, "sandbox:", err)
+		os.Exit(126)
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		fail(err)
+	}
+	if err := setupSandbox(workDir, files, writableDir); err != nil {
+		fail(err)
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		fail(err)
+	}
+	if err := syscall.Exec(path, args, os.Environ()); err != nil {
+		fail(err)
+	}
+}
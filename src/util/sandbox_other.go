@@ -0,0 +1,29 @@
+//go:build !linux
+
+package program
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Argument used to re-execute play as the sandbox helper
+const SandboxCommand = "__sandbox"
+
+// Returns whether the sandbox is supported on this platform
+func SandboxSupported() bool {
+	return false
+}
+
+// Wrap the given process so that it runs inside the sandbox
+func sandbox(cmd *exec.Cmd, files []string, writableDir string) error {
+	return errors.New("sandbox mode is only supported on Linux")
+}
+
+// Entry point of the sandbox helper
+func SandboxMain(args []string) {
+	fmt.Fprintln(os.Stderr, "sandbox: only supported on Linux")
+	os.Exit(126)
+}
@@ -0,0 +1,202 @@
+package program
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Size of the chunks in which the standard input is copied
+const captureChunkSize = 32 << 10
+
+// Captures, by the absolute path of their file
+var captures sync.Map
+
+// Input being captured into a temporary file in the background, such as the standard input.
+// The bytes are copied as they are, as soon as they are read, up to MaxSize bytes (no limit if 0).
+// Beyond MaxSize the newest bytes are discarded, unless the capture is Rolling, in which case
+// the oldest ones are, so that the file holds about the last MaxSize bytes of the input.
+type Capture struct {
+	File    string
+	MaxSize int64
+	Rolling bool
+	f       *os.File
+	mu      sync.Mutex
+	fileMu  sync.Mutex
+	state   CaptureState
+	done    chan struct{}
+}
+
+// State of a capture.
+// Size is the number of bytes held by the file, Truncated tells whether bytes were discarded
+// because of the maximum size, and Discarded is the number of oldest bytes discarded by a rolling capture.
+type CaptureState struct {
+	Size      int64
+	Discarded int64
+	Done      bool
+	Truncated bool
+	Err       error
+}
+
+// Start capturing the given reader into a new temporary file, keeping at most maxSize bytes (no limit if 0).
+// The bytes beyond the maximum size are read and discarded, so that the producer is not blocked.
+func CaptureInput(r io.Reader, maxSize int64, rolling bool) (*Capture, error) {
+	f, err := os.CreateTemp("", "play")
+	if err != nil {
+		return nil, err
+	}
+	c := &Capture{File: f.Name(), MaxSize: maxSize, Rolling: rolling, f: f, done: make(chan struct{})}
+	if path, err := filepath.Abs(c.File); err == nil {
+		captures.Store(path, c)
+	}
+	go c.copy(r)
+	return c, nil
+}
+
+// Returns the capture writing to the file of the given absolute path, if any
+func captureOf(path string) (*Capture, bool) {
+	c, ok := captures.Load(path)
+	if !ok {
+		return nil, false
+	}
+	return c.(*Capture), true
+}
+
+// Helper function to copy the given reader into the file of the capture until the end of the reader
+func (c *Capture) copy(r io.Reader) {
+	defer close(c.done)
+	defer func() { c.f.Close() }()
+	buf := make([]byte, captureChunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if werr := c.write(buf[:n]); werr != nil {
+				c.finish(werr)
+				return
+			}
+		}
+		if err == io.EOF {
+			c.finish(nil)
+			return
+		}
+		if err != nil {
+			c.finish(err)
+			return
+		}
+	}
+}
+
+// Helper function to write the given bytes to the file, up to the maximum size.
+// Rolling captures discard their oldest bytes once they exceed the maximum size by a quarter,
+// so that the file is not rewritten on every write.
+func (c *Capture) write(data []byte) error {
+	size := c.State().Size
+
+	truncated := false
+	if !c.Rolling && c.MaxSize > 0 && size+int64(len(data)) > c.MaxSize {
+		data = data[:c.MaxSize-size]
+		truncated = true
+	}
+	if len(data) > 0 {
+		if _, err := c.f.Write(data); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.state.Size += int64(len(data))
+	c.state.Truncated = c.state.Truncated || truncated
+	size = c.state.Size
+	c.mu.Unlock()
+
+	if c.Rolling && c.MaxSize > 0 && size > c.MaxSize+c.MaxSize/4 {
+		return c.compact(size)
+	}
+	return nil
+}
+
+// Helper function to replace the file with one holding its last MaxSize bytes,
+// starting at the beginning of a line if there is one within the first chunk
+func (c *Capture) compact(size int64) error {
+	c.fileMu.Lock()
+	defer c.fileMu.Unlock()
+
+	start := size - c.MaxSize
+	buf := make([]byte, captureChunkSize)
+	n, err := c.f.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+		start += int64(i + 1)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(c.File), "play")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, io.NewSectionReader(c.f, start, size-start)); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), c.File); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	c.f.Close()
+	c.f = f
+
+	c.mu.Lock()
+	c.state.Size -= start
+	c.state.Discarded += start
+	c.state.Truncated = true
+	c.mu.Unlock()
+	return nil
+}
+
+// Helper function to mark the capture as done, with the given error if any
+func (c *Capture) finish(err error) {
+	c.mu.Lock()
+	c.state.Done = true
+	c.state.Err = err
+	c.mu.Unlock()
+}
+
+// Returns the current state of the capture
+func (c *Capture) State() CaptureState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Returns a channel closed once the whole input is captured
+func (c *Capture) Done() <-chan struct{} {
+	return c.done
+}
+
+// Returns a new temporary file holding a copy of the input captured so far
+func (c *Capture) Snapshot() (string, error) {
+	c.fileMu.Lock()
+	defer c.fileMu.Unlock()
+
+	src, err := os.Open(c.File)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	f, err := os.CreateTemp("", "play")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(f, io.LimitReader(src, c.State().Size))
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
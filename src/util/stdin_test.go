@@ -0,0 +1,128 @@
+package program
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Helper function to capture the given input in chunks of the given size, waiting for the whole input to be captured
+func captureAll(t *testing.T, input string, chunk int, maxSize int64, rolling bool) (*Capture, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := CaptureInput(r, maxSize, rolling)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(c.File) })
+	for i := 0; i < len(input); i += chunk {
+		end := i + chunk
+		if end > len(input) {
+			end = len(input)
+		}
+		if _, err := w.WriteString(input[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+	<-c.Done()
+	data, err := os.ReadFile(c.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, string(data)
+}
+
+func TestCaptureCompaction(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; sb.Len() < 4*captureChunkSize; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	input := sb.String()
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		chunk   int
+	}{
+		{"small chunks", 1000, 100},
+		{"large chunks", 1000, captureChunkSize},
+		{"larger than a chunk", 2 * captureChunkSize, 4096},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, data := captureAll(t, input, tt.chunk, tt.maxSize, true)
+			state := c.State()
+			if state.Err != nil {
+				t.Fatal(state.Err)
+			}
+			if !state.Truncated {
+				t.Errorf("capture is not truncated")
+			}
+			if state.Size != int64(len(data)) {
+				t.Errorf("size = %d, file holds %d bytes", state.Size, len(data))
+			}
+			if state.Discarded+state.Size != int64(len(input)) {
+				t.Errorf("discarded %d + size %d bytes, want %d", state.Discarded, state.Size, len(input))
+			}
+			// the file holds the end of the input, starting at a line, with at most a quarter more than the maximum size
+			if !strings.HasSuffix(input, data) {
+				t.Errorf("file does not hold the end of the input")
+			}
+			if state.Discarded > 0 && input[state.Discarded-1] != '\n' {
+				t.Errorf("file does not start at the beginning of a line")
+			}
+			if max := tt.maxSize + tt.maxSize/4 + int64(tt.chunk); state.Size > max {
+				t.Errorf("file holds %d bytes, want at most %d", state.Size, max)
+			}
+		})
+	}
+}
+
+func TestCaptureMaxSize(t *testing.T) {
+	input := strings.Repeat("abcdefghij\n", 1000)
+	tests := []struct {
+		name      string
+		maxSize   int64
+		want      string
+		truncated bool
+	}{
+		{"no limit", 0, input, false},
+		{"within the limit", int64(len(input)), input, false},
+		{"beyond the limit", 25, input[:25], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, data := captureAll(t, input, 1000, tt.maxSize, false)
+			if data != tt.want {
+				t.Errorf("file holds %d bytes, want %d", len(data), len(tt.want))
+			}
+			if state := c.State(); state.Truncated != tt.truncated || state.Size != int64(len(tt.want)) || state.Discarded != 0 {
+				t.Errorf("state = %+v, want size %d and truncated %v", state, len(tt.want), tt.truncated)
+			}
+		})
+	}
+}
+
+func TestCaptureSnapshot(t *testing.T) {
+	input := strings.Repeat("0123456789", 10000)
+	c, _ := captureAll(t, input, 4096, 1000, true)
+	snapshot, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(snapshot)
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// without new lines the oldest bytes are discarded as they are
+	if !bytes.HasSuffix([]byte(input), data) || int64(len(data)) != c.State().Size {
+		t.Errorf("snapshot holds %d bytes which are not the end of the input", len(data))
+	}
+}
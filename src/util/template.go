@@ -0,0 +1,106 @@
+package program
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Kinds of parts of argument templates
+const (
+	TemplateOptions    = "options"
+	TemplateExpression = "expression"
+	TemplateFiles      = "files"
+	TemplateLiteral    = "literal"
+)
+
+// Part of an argument template: a placeholder or a literal word.
+// Index is the index of the expression of expression placeholders, from 0.
+type TemplatePart struct {
+	Kind  string
+	Index int
+	Word  string
+}
+
+// Placeholders of templates; any other word, such as the {} of find -exec, is a literal word
+var placeholderRegex = regexp.MustCompile(`^\{(?:(options|files)|(expr)([0-9]*))\}$`)
+
+// Maximum number of expressions of a template
+const maxTemplateExpressions = 99
+
+// Parse the given argument template, such as "{options} -e {expr1} -e {expr2} -- {files}".
+// {expr} is the same as {expr1}, expressions must be numbered from 1 to at most 99 without gaps,
+// and each placeholder may appear at most once.
+func ParseTemplate(template string) ([]TemplatePart, error) {
+	words, err := SplitOptions(template)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []TemplatePart
+	seen := make(map[string]bool)
+	expressions := 0
+	for _, word := range words {
+		match := placeholderRegex.FindStringSubmatch(word)
+		if match == nil {
+			parts = append(parts, TemplatePart{Kind: TemplateLiteral, Word: word})
+			continue
+		}
+		name, number := match[1]+match[2], match[3]
+		switch name {
+		case "options", "files":
+			if seen[word] {
+				return nil, fmt.Errorf("%s appears more than once", word)
+			}
+			seen[word] = true
+			kind := TemplateOptions
+			if name == "files" {
+				kind = TemplateFiles
+			}
+			parts = append(parts, TemplatePart{Kind: kind, Word: word})
+		case "expr":
+			index := 0
+			if len(number) > 0 {
+				n, err := strconv.Atoi(number)
+				if err != nil || n < 1 || n > maxTemplateExpressions {
+					return nil, fmt.Errorf("invalid placeholder %s, expressions are numbered from 1 to %d", word, maxTemplateExpressions)
+				}
+				index = n - 1
+			}
+			// {expr} and {expr1} are the same placeholder
+			key := fmt.Sprintf("{expr%d}", index+1)
+			if seen[key] {
+				return nil, fmt.Errorf("%s appears more than once", key)
+			}
+			seen[key] = true
+			if index >= expressions {
+				expressions = index + 1
+			}
+			parts = append(parts, TemplatePart{Kind: TemplateExpression, Index: index, Word: word})
+		}
+	}
+
+	used := make([]bool, expressions)
+	for _, part := range parts {
+		if part.Kind == TemplateExpression {
+			used[part.Index] = true
+		}
+	}
+	for i, ok := range used {
+		if !ok {
+			return nil, fmt.Errorf("{expr%d} is missing", i+1)
+		}
+	}
+	return parts, nil
+}
+
+// Returns the number of expressions of the given parts of a template
+func TemplateExpressions(parts []TemplatePart) int {
+	n := 0
+	for _, part := range parts {
+		if part.Kind == TemplateExpression && part.Index >= n {
+			n = part.Index + 1
+		}
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+package program
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		want     []TemplatePart
+		wantErr  bool
+	}{
+		{
+			template: "{options} -e {expr1} -e {expr2} -- {files}",
+			want: []TemplatePart{
+				{Kind: TemplateOptions, Word: "{options}"},
+				{Kind: TemplateLiteral, Word: "-e"},
+				{Kind: TemplateExpression, Index: 0, Word: "{expr1}"},
+				{Kind: TemplateLiteral, Word: "-e"},
+				{Kind: TemplateExpression, Index: 1, Word: "{expr2}"},
+				{Kind: TemplateLiteral, Word: "--"},
+				{Kind: TemplateFiles, Word: "{files}"},
+			},
+		},
+		{
+			template: "{options} {expr} A-Z",
+			want: []TemplatePart{
+				{Kind: TemplateOptions, Word: "{options}"},
+				{Kind: TemplateExpression, Index: 0, Word: "{expr}"},
+				{Kind: TemplateLiteral, Word: "A-Z"},
+			},
+		},
+		{
+			template: `. -name {expr} -exec ls {} \;`,
+			want: []TemplatePart{
+				{Kind: TemplateLiteral, Word: "."},
+				{Kind: TemplateLiteral, Word: "-name"},
+				{Kind: TemplateExpression, Index: 0, Word: "{expr}"},
+				{Kind: TemplateLiteral, Word: "-exec"},
+				{Kind: TemplateLiteral, Word: "ls"},
+				{Kind: TemplateLiteral, Word: "{}"},
+				{Kind: TemplateLiteral, Word: ";"},
+			},
+		},
+		{
+			template: "-I{} {expr} {options1} {name}",
+			want: []TemplatePart{
+				{Kind: TemplateLiteral, Word: "-I{}"},
+				{Kind: TemplateExpression, Index: 0, Word: "{expr}"},
+				{Kind: TemplateLiteral, Word: "{options1}"},
+				{Kind: TemplateLiteral, Word: "{name}"},
+			},
+		},
+		{template: "{expr99}", wantErr: true},
+		{template: "{expr2}", wantErr: true},
+		{template: "{expr0}", wantErr: true},
+		{template: "{expr100}", wantErr: true},
+		{template: "{expr999999999999999}", wantErr: true},
+		{template: "{expr99999999999999999999999}", wantErr: true},
+		{template: "{options} {options}", wantErr: true},
+		{template: "{files} {files} {expr}", wantErr: true},
+		{template: "{expr1} -o {expr1}", wantErr: true},
+		{template: "{expr} {expr1}", wantErr: true},
+		{template: "'{expr}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := ParseTemplate(tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTemplate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateExpressions(t *testing.T) {
+	tests := []struct {
+		template string
+		want     int
+	}{
+		{"{options} {files}", 0},
+		{"{expr}", 1},
+		{"{expr2} {expr1} {expr3}", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			parts, err := ParseTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := TemplateExpressions(parts); got != tt.want {
+				t.Errorf("TemplateExpressions() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,439 @@
+package program
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/itchyny/gojq"
+)
+
+// Maximum duration and output of the validation of an expression by an external program
+const (
+	validationTimeout   = time.Second
+	maxValidationOutput = 64 << 10
+)
+
+// Maximum duration of the validation of a filter by jq, which executes it
+const jqValidationTimeout = 100 * time.Millisecond
+
+// Validator of the expressions of an implementation of a program, returning the error in the expression of the given command, if any
+type validator func(ctx context.Context, command Command, settings Settings) *Diagnostic
+
+// Validators of the expressions of external programs, by implementation
+var validators = map[string]validator{
+	"gawk":  validateGawk,
+	"mawk":  validateMawk,
+	"jq":    validateJqCompile,
+	"sed":   validateSed,
+	"gsed":  validateSed,
+	"grep":  validateGrep,
+	"ggrep": validateGrep,
+	"egrep": validateEgrep,
+}
+
+// Validate the expressions of the given commands, returning the index of the first invalid command along with its error
+func ValidatePipeline(ctx context.Context, commands []Command, settings Settings) (int, *Diagnostic) {
+	for i, command := range commands {
+		b := command.Backend()
+		if b == nil {
+			continue
+		}
+		if d := validate(ctx, b, command, settings); d != nil {
+			return i, d
+		}
+	}
+	return -1, nil
+}
+
+// Helper function to validate the expressions of the given command, remembering the outcome in the cache, if any,
+// so that evaluating a cached command again does not execute its validator
+func validate(ctx context.Context, b Backend, command Command, settings Settings) *Diagnostic {
+	key := ""
+	if settings.Cache != nil {
+		// the outcome does not depend on the input files
+		command := command
+		command.Files = nil
+		key, _ = settings.Cache.key(command, settings, nil)
+		if len(key) > 0 {
+			key = "validation " + key
+			if res, ok := settings.Cache.get(key); ok {
+				return res.Diagnostic
+			}
+		}
+	}
+	d := b.Validate(ctx, command, settings)
+	// validations interrupted by a newer evaluation are not worth remembering
+	if len(key) > 0 && ctx.Err() == nil {
+		settings.Cache.put(key, Result{Diagnostic: d})
+	}
+	return d
+}
+
+// Helper function returning the name of the implementation executing the given command, such as "gawk"
+// when awk is a link to gawk, or an empty string when the program is run through another one, as with "busybox awk"
+func implementation(command Command) string {
+	if len(command.PathArgs) > 0 {
+		return ""
+	}
+	path, err := exec.LookPath(executableOf(command))
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	name := filepath.Base(path)
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(strings.ToLower(name), ".exe")
+	}
+	return name
+}
+
+// Helper function returning the executable of the given command
+func executableOf(command Command) string {
+	if len(command.Path) > 0 {
+		return command.Path
+	}
+	return command.Program
+}
+
+// Helper function to run the executable of the given command with the given arguments on empty input, to validate its expression.
+// Returns false if the validation did not complete.
+func check(ctx context.Context, command Command, settings Settings, args ...string) (Result, bool) {
+	cmd := exec.Command(executableOf(command), args...)
+	if len(command.Env) > 0 {
+		cmd.Env = append(os.Environ(), command.Env...)
+	}
+	if settings.Sandbox {
+		if err := sandbox(cmd, nil, ""); err != nil {
+			return Result{}, false
+		}
+	}
+	limits := settings
+	if limits.Timeout <= 0 || limits.Timeout > validationTimeout {
+		limits.Timeout = validationTimeout
+	}
+	limits.MaxOutput = maxValidationOutput
+	res, err := execute(ctx, cmd, limits)
+	if err != nil || res.TimedOut || res.Truncated || len(res.Signal) > 0 {
+		return res, false
+	}
+	return res, true
+}
+
+// Helper function returning the byte offsets of the start and the end of the given line of the expression, starting at 1
+func lineOffsets(expression string, line int) (int, int) {
+	start := 0
+	for ; line > 1; line-- {
+		i := strings.IndexByte(expression[start:], '\n')
+		if i < 0 {
+			return len(expression), len(expression)
+		}
+		start += i + 1
+	}
+	end := len(expression)
+	if i := strings.IndexByte(expression[start:], '\n'); i >= 0 {
+		end = start + i
+	}
+	return start, end
+}
+
+// Helper function to return the first line of the given text
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
+
+// Helper function to split the given option into its letters if it is made of short options, such as -nE
+func shortOptions(word string) (string, bool) {
+	if len(word) < 2 || word[0] != '-' || word[1] == '-' {
+		return "", false
+	}
+	return word[1:], true
+}
+
+// Options of gawk changing the way programs are parsed
+var gawkSyntaxOptions = map[string]bool{"-P": true, "--posix": true, "-c": true, "--traditional": true}
+
+// Location of the errors of gawk in programs given on the command line
+var gawkLocation = regexp.MustCompile(`^\S+: cmd\. line:(\d+): (.*)$`)
+
+// Validate the program of the given command with gawk, which only parses programs when pretty printing them
+func validateGawk(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	words, err := SplitOptions(command.Options)
+	if err != nil {
+		return nil
+	}
+	args := []string{"--pretty-print=" + os.DevNull}
+	for _, word := range words {
+		if gawkSyntaxOptions[word] {
+			args = append(args, word)
+		}
+	}
+	args = append(args, "--", command.Expression)
+	res, ok := check(ctx, command, settings, args...)
+	if !ok || res.ExitCode == 0 {
+		return nil
+	}
+	return gawkDiagnostic(command.Expression, res.Stderr)
+}
+
+// Returns the diagnostic of the given errors of gawk, if any
+func gawkDiagnostic(expression string, stderr string) *Diagnostic {
+	lines := strings.Split(stderr, "\n")
+	for i, l := range lines {
+		m := gawkLocation.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		start, end := lineOffsets(expression, line)
+		// the line at fault is followed by a caret pointing at the error
+		if i+1 < len(lines) {
+			if next := gawkLocation.FindStringSubmatch(lines[i+1]); next != nil && next[1] == m[1] {
+				caret := strings.Index(next[2], "^ ")
+				if caret >= 0 && len(strings.TrimSpace(next[2][:caret])) == 0 {
+					offset := start + caret
+					if offset > end {
+						offset = end
+					}
+					return &Diagnostic{Message: next[2][caret+2:], Expression: expression, Offset: offset}
+				}
+			}
+		}
+		return &Diagnostic{Message: m[2], Expression: expression, Offset: start}
+	}
+	return nil
+}
+
+// Location of the errors of mawk, and token they are near, if any
+var (
+	mawkLocation = regexp.MustCompile(`^\S+: (?:line )?(\d+): (.*)$`)
+	mawkToken    = regexp.MustCompile(` near (.+)$|'(.)'$`)
+)
+
+// Validate the program of the given command with mawk, which only parses programs when dumping them
+func validateMawk(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	res, ok := check(ctx, command, settings, "-W", "dump", "--", command.Expression)
+	if !ok || res.ExitCode == 0 {
+		return nil
+	}
+	m := mawkLocation.FindStringSubmatch(firstLine(res.Stderr))
+	if m == nil {
+		return nil
+	}
+	line, _ := strconv.Atoi(m[1])
+	start, end := lineOffsets(command.Expression, line)
+	offset := end
+	if token := mawkToken.FindStringSubmatch(m[2]); token != nil {
+		near := token[1] + token[2]
+		if i := strings.Index(command.Expression[start:end], near); i >= 0 && near != "end of line" && near != "end of file" {
+			offset = start + i
+		}
+	}
+	return &Diagnostic{Message: m[2], Expression: command.Expression, Offset: offset}
+}
+
+// Location of the compile errors of jq
+var jqLocation = regexp.MustCompile(`^jq: error: (.*) at <top-level>, line (\d+)`)
+
+// Options of jq defining a variable, followed by its name and its value
+var jqVariableOptions = map[string]bool{"--arg": true, "--argjson": true, "--slurpfile": true, "--rawfile": true}
+
+// Validate the filter of the given command by compiling it with gojq, as running jq would execute the filter.
+// jq defines a few functions unknown to gojq, so the filters gojq cannot compile are compiled by jq on null input,
+// which then executes them for a short time if they are valid. The position of the errors is told by the parser of gojq.
+func validateJqCompile(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	words, err := SplitOptions(command.Options)
+	if err != nil {
+		return nil
+	}
+	variables := []string{"$ARGS"}
+	for i, word := range words {
+		// the filter is then read from a file
+		if letters, ok := shortOptions(word); word == "--from-file" || ok && strings.ContainsRune(letters, 'f') {
+			return nil
+		}
+		if jqVariableOptions[word] && i+1 < len(words) {
+			variables = append(variables, "$"+words[i+1])
+		}
+	}
+	if query, err := gojq.Parse(command.Expression); err == nil {
+		if _, err := gojq.Compile(query, gojq.WithVariables(variables)); err == nil {
+			return nil
+		}
+	}
+
+	args := append(append([]string{"-n"}, words...), command.Expression)
+	settings.Timeout = jqValidationTimeout
+	res, ok := check(ctx, command, settings, args...)
+	if !ok || res.ExitCode != jqExitCompileError {
+		return nil
+	}
+
+	m := jqLocation.FindStringSubmatch(firstLine(res.Stderr))
+	if m == nil {
+		return nil
+	}
+	d := &Diagnostic{Message: m[1], Expression: command.Expression}
+	if parsed := validateJq(command); parsed != nil {
+		d.Offset = parsed.Offset
+	} else if strings.HasSuffix(m[1], " is not defined") {
+		// functions are named along with their arity, as in f/1
+		name, _, _ := strings.Cut(strings.TrimSuffix(m[1], " is not defined"), "/")
+		d.Offset = strings.Index(command.Expression, name)
+	} else {
+		line, _ := strconv.Atoi(m[2])
+		d.Offset, _ = lineOffsets(command.Expression, line)
+	}
+	if d.Offset < 0 {
+		d.Offset = 0
+	}
+	return d
+}
+
+// Options of sed changing the way scripts are parsed, by letter for short options
+var (
+	sedSyntaxOptions = map[string]bool{"--regexp-extended": true, "--posix": true, "--null-data": true}
+	sedSyntaxLetters = "Erz"
+)
+
+// Location of the errors of GNU sed, and of the errors of BSD sed
+var (
+	sedLocation    = regexp.MustCompile(`^\S+: -e expression #\d+, char (\d+): (.*)$`)
+	bsdSedLocation = regexp.MustCompile(`^\S+: \d+: ".*": (.*)$`)
+)
+
+// Validate the script of the given command by running sed on empty input, which parses the script without executing it
+func validateSed(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	words, err := SplitOptions(command.Options)
+	if err != nil {
+		return nil
+	}
+	args := []string{"-n"}
+	for _, word := range words {
+		letters, short := shortOptions(word)
+		switch {
+		case strings.HasPrefix(word, "--expression") || strings.HasPrefix(word, "--file"):
+			// the script is then made of several parts or read from a file
+			return nil
+		case sedSyntaxOptions[word]:
+			args = append(args, word)
+		case short:
+			for _, letter := range letters {
+				if letter == 'e' || letter == 'f' {
+					return nil
+				}
+				if letter == 'i' || letter == 'l' {
+					// the rest of the word is the value of the option
+					break
+				}
+				if strings.ContainsRune(sedSyntaxLetters, letter) {
+					args = append(args, "-"+string(letter))
+				}
+			}
+		}
+	}
+	args = append(args, "-e", command.Expression)
+	res, ok := check(ctx, command, settings, args...)
+	if !ok || res.ExitCode == 0 {
+		return nil
+	}
+
+	line := firstLine(res.Stderr)
+	if m := sedLocation.FindStringSubmatch(line); m != nil {
+		// the position is the number of characters read when the error was found
+		char, _ := strconv.Atoi(m[1])
+		runes := []rune(command.Expression)
+		offset := len(string(runes[:clamp(char-1, 0, len(runes))]))
+		return &Diagnostic{Message: m[2], Expression: command.Expression, Offset: offset}
+	}
+	if m := bsdSedLocation.FindStringSubmatch(line); m != nil {
+		return &Diagnostic{Message: m[1], Expression: command.Expression, Offset: len(command.Expression)}
+	}
+	return nil
+}
+
+// Helper function to bring the given value within the given bounds
+func clamp(value int, low int, high int) int {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
+
+// Codes of the errors of Go regular expressions which are errors in POSIX extended regular expressions as well.
+// Other errors are left out, as the syntax of Go is stricter, for instance about \< or a lone ).
+var extendedRegexErrors = map[syntax.ErrorCode]bool{
+	syntax.ErrMissingBracket:    true,
+	syntax.ErrMissingParen:      true,
+	syntax.ErrTrailingBackslash: true,
+	syntax.ErrInvalidCharRange:  true,
+}
+
+// Validate the pattern of the given command with the Go regexp package when grep uses extended regular expressions
+func validateGrep(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	return validateGrepPattern(command, false)
+}
+
+// Validate the pattern of the given command with the Go regexp package, egrep using extended regular expressions
+func validateEgrep(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	return validateGrepPattern(command, true)
+}
+
+// Helper function to validate the pattern of the given command of grep if it is an extended regular expression
+func validateGrepPattern(command Command, extended bool) *Diagnostic {
+	words, err := SplitOptions(command.Options)
+	if err != nil {
+		return nil
+	}
+	for _, word := range words {
+		letters, short := shortOptions(word)
+		switch {
+		case word == "--extended-regexp":
+			extended = true
+		case word == "--basic-regexp" || word == "--fixed-strings" || word == "--perl-regexp":
+			extended = false
+		case strings.HasPrefix(word, "--regexp") || strings.HasPrefix(word, "--file"):
+			// the pattern is then given along with others or read from a file
+			return nil
+		case short:
+		letters:
+			for _, letter := range letters {
+				switch letter {
+				case 'E':
+					extended = true
+				case 'G', 'F', 'P':
+					extended = false
+				case 'e', 'f':
+					return nil
+				case 'A', 'B', 'C', 'm', 'd', 'D':
+					// the rest of the word is the value of the option
+					break letters
+				}
+			}
+		}
+	}
+	if !extended {
+		return nil
+	}
+
+	_, err = regexp.Compile(command.Expression)
+	var syntaxError *syntax.Error
+	if errors.As(err, &syntaxError) && extendedRegexErrors[syntaxError.Code] {
+		return regexDiagnostic(command.Expression, err)
+	}
+	return nil
+}
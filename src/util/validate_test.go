@@ -0,0 +1,76 @@
+package program
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestValidateJqCompile(t *testing.T) {
+	if _, err := exec.LookPath("jq"); err != nil {
+		t.Skip("jq not found")
+	}
+	tests := []struct {
+		options    string
+		expression string
+		wantOffset int
+		valid      bool
+	}{
+		{"", ".a | map(. + 1)", 0, true},
+		{"", "range(1e9)", 0, true},
+		{"", "[limit(3; repeat(.))]", 0, true},
+		{"", "input_line_number, range(1e9)", 0, true},
+		{"--arg x 1", "$x", 0, true},
+		{"", "$x", 0, false},
+		{"", ".a | foo(1)", 5, false},
+		{"", ".a | (", 6, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			start := time.Now()
+			d := validateJqCompile(context.Background(), Command{Program: "jq", Options: tt.options, Expression: tt.expression}, Settings{Timeout: 5 * time.Second})
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("validation took %s", elapsed)
+			}
+			if (d == nil) != tt.valid {
+				t.Fatalf("validateJqCompile() = %+v, want valid %v", d, tt.valid)
+			}
+			if d != nil && d.Offset != tt.wantOffset {
+				t.Errorf("offset = %d, want %d (%s)", d.Offset, tt.wantOffset, d.Message)
+			}
+		})
+	}
+}
+
+// Backend counting its validations, rejecting the expression "invalid"
+type countingBackend struct {
+	externalBackend
+	validations *int
+}
+
+func (b countingBackend) Validate(ctx context.Context, command Command, settings Settings) *Diagnostic {
+	*b.validations++
+	if command.Expression == "invalid" {
+		return &Diagnostic{Message: "invalid", Expression: command.Expression}
+	}
+	return nil
+}
+
+func TestValidatePipelineCache(t *testing.T) {
+	validations := 0
+	backends["counting"] = countingBackend{validations: &validations}
+	defer delete(backends, "counting")
+
+	settings := Settings{Cache: NewCache(8)}
+	for _, expression := range []string{"valid", "invalid", "valid", "invalid"} {
+		command := Command{Program: "test", Expression: expression, Engine: "counting"}
+		stage, d := ValidatePipeline(context.Background(), []Command{command}, settings)
+		if (d != nil) != (expression == "invalid") || (d != nil && stage != 0) {
+			t.Errorf("ValidatePipeline(%q) = %d, %+v", expression, stage, d)
+		}
+	}
+	if validations != 2 {
+		t.Errorf("validated %d times, want 2", validations)
+	}
+}
@@ -0,0 +1,96 @@
+package program
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Options printing the version of programs, tried in turn
+var versionOptions = [][]string{{"--version"}, {"-W", "version"}, {"-V"}}
+
+// Maximum duration of each attempt at printing the version of a program
+const versionTimeout = 2 * time.Second
+
+// Modules of the builtin engines, by engine
+var engineModules = map[string]string{
+	"gojq":  "github.com/itchyny/gojq",
+	"goawk": "github.com/benhoyt/goawk",
+}
+
+// Returns the path of the executable of the program, with symbolic links resolved
+func (p Program) ResolvedPath() (string, error) {
+	path, err := exec.LookPath(p.Executable())
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path, nil
+}
+
+// Returns the version of the program, as the first line it prints with the first of
+// the version options it accepts, or an empty string if it accepts none
+func (p Program) Version(ctx context.Context) string {
+	settings := Settings{Timeout: versionTimeout, MaxOutput: 64 << 10}
+	for _, options := range versionOptions {
+		args := append(append([]string{p.Executable()}, p.PathArgs...), options...)
+		res, err := execute(ctx, exec.Command(args[0], args[1:]...), settings)
+		if err != nil || !res.Success() {
+			continue
+		}
+		// some programs print their version on stderr
+		for _, line := range strings.Split(res.Stdout+"\n"+res.Stderr, "\n") {
+			if line = strings.TrimSpace(line); len(line) > 0 {
+				return line
+			}
+		}
+	}
+	return ""
+}
+
+// Returns the version of the given builtin engine
+func EngineVersion(engine string) string {
+	if engine == "regexp" {
+		return "Go regexp " + runtime.Version()
+	}
+	version := "unknown version"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == engineModules[engine] {
+				version = dep.Version
+			}
+		}
+	}
+	return engine + " " + version
+}
+
+// Returns the flavor of the implementation of a program, given its path and version:
+// "busybox", "GNU" or "BSD", or an empty string if unknown.
+// BSD programs often accept none of the version options, so those are assumed to be BSD on BSD systems.
+func Flavor(path string, version string) string {
+	switch {
+	case filepath.Base(path) == "busybox" || strings.Contains(version, "BusyBox"):
+		return "busybox"
+	case strings.Contains(version, "BSD"):
+		return "BSD"
+	case strings.Contains(version, "GNU"):
+		return "GNU"
+	case len(version) == 0 && bsdSystems[runtime.GOOS]:
+		return "BSD"
+	}
+	return ""
+}
+
+// Systems whose programs are BSD ones by default
+var bsdSystems = map[string]bool{
+	"darwin":  true,
+	"freebsd": true,
+	"netbsd":  true,
+	"openbsd": true,
+}
@@ -0,0 +1,133 @@
+package program
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/paololazzari/play/src/diff"
+)
+
+// Modification made to an input file by an evaluation in scratch mode.
+// The diff is computed along with the evaluation, so that it is not computed when showing the change.
+type Change struct {
+	Path     string
+	Original string
+	Modified string
+	diff     string
+}
+
+// Returns the change as a unified diff
+func (c Change) Diff() string {
+	if len(c.diff) > 0 {
+		return c.diff
+	}
+	name := filepath.ToSlash(c.Path)
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return diff.Unified("a"+name, "b"+name, c.Original, c.Modified)
+}
+
+// Scratch copy of the input files of a command
+type workspace struct {
+	dir       string
+	files     []string
+	copies    map[string]string
+	originals map[string]string
+}
+
+// Workspace constructor, copying every file into a fresh temp directory
+func newWorkspace(files []string) (*workspace, error) {
+	dir, err := os.MkdirTemp("", "play-scratch")
+	if err != nil {
+		return nil, err
+	}
+	w := &workspace{
+		dir:       dir,
+		files:     files,
+		copies:    make(map[string]string),
+		originals: make(map[string]string),
+	}
+
+	for i, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			w.remove()
+			return nil, err
+		}
+		// keep the base name so that messages of the program look familiar
+		scratchFile := filepath.Join(dir, strconv.Itoa(i), filepath.Base(file))
+		if err := os.Mkdir(filepath.Dir(scratchFile), 0755); err != nil {
+			w.remove()
+			return nil, err
+		}
+		if err := os.WriteFile(scratchFile, contents, 0644); err != nil {
+			w.remove()
+			return nil, err
+		}
+		w.copies[file] = scratchFile
+		w.originals[file] = string(contents)
+	}
+	return w, nil
+}
+
+// Returns the command operating on the scratch copies instead of the real files
+func (w *workspace) command(command Command) Command {
+	files := make([]string, len(command.Files))
+	for i, file := range command.Files {
+		files[i] = w.copies[file]
+	}
+	command.Files = files
+	return command
+}
+
+// Returns the modifications made to the scratch copies
+func (w *workspace) changes() []Change {
+	var changes []Change
+	for _, file := range w.files {
+		modified, err := os.ReadFile(w.copies[file])
+		if err != nil {
+			continue
+		}
+		if string(modified) != w.originals[file] {
+			change := Change{
+				Path:     file,
+				Original: w.originals[file],
+				Modified: string(modified),
+			}
+			change.diff = change.Diff()
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Delete the scratch copies
+func (w *workspace) remove() {
+	_ = os.RemoveAll(w.dir)
+}
+
+// Write the given modifications to the real files.
+// A file is left untouched if it changed since the evaluation.
+func ApplyChanges(changes []Change) error {
+	for _, change := range changes {
+		current, err := os.ReadFile(change.Path)
+		if err != nil {
+			return err
+		}
+		if string(current) != change.Original {
+			return fmt.Errorf("%s has changed since the evaluation", change.Path)
+		}
+		stat, err := os.Stat(change.Path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(change.Path, []byte(change.Modified), stat.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}